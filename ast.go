@@ -0,0 +1,103 @@
+package goculator
+
+import "math"
+
+// node is a node of the abstract syntax tree of Expression.
+type node interface {
+	span() Span
+}
+
+// numberNode is a number literal. literal is the value of TokenTypeNUM token.
+type numberNode struct {
+	value   float64
+	literal string
+	s       Span
+}
+
+// variableNode is a variable with its path. The first element of path is always a name.
+type variableNode struct {
+	path []pathElementNode
+	s    Span
+}
+
+// pathElementNode is a member name, or an index expression if index is not nil.
+type pathElementNode struct {
+	name  string
+	index node
+}
+
+// binaryNode is an operation with two operands. op is one of TokenTypePLUS, TokenTypeMINUS,
+// TokenTypeMULTI and TokenTypeDIV.
+type binaryNode struct {
+	op    TokenType
+	left  node
+	right node
+	s     Span
+	// opPos is Position of the operator.
+	opPos Position
+}
+
+// unaryNode is an operation with one operand. op is TokenTypePLUS or TokenTypeMINUS.
+type unaryNode struct {
+	op      TokenType
+	operand node
+	s       Span
+}
+
+func (n *numberNode) span() Span   { return n.s }
+func (n *variableNode) span() Span { return n.s }
+func (n *binaryNode) span() Span   { return n.s }
+func (n *unaryNode) span() Span    { return n.s }
+
+// equalNodes reports whether a and b have the same structure and values ignoring Span.
+func equalNodes(a, b node) bool {
+	switch a := a.(type) {
+	case nil:
+		return b == nil
+	case *numberNode:
+		b, ok := b.(*numberNode)
+		return ok && (a.value == b.value && math.Signbit(a.value) == math.Signbit(b.value))
+	case *variableNode:
+		b, ok := b.(*variableNode)
+		if !ok || len(a.path) != len(b.path) {
+			return false
+		}
+		for i := range a.path {
+			if a.path[i].name != b.path[i].name || (a.path[i].index == nil) != (b.path[i].index == nil) {
+				return false
+			}
+			if a.path[i].index != nil && !equalNodes(a.path[i].index, b.path[i].index) {
+				return false
+			}
+		}
+		return true
+	case *binaryNode:
+		b, ok := b.(*binaryNode)
+		return ok && a.op == b.op && equalNodes(a.left, b.left) && equalNodes(a.right, b.right)
+	case *unaryNode:
+		b, ok := b.(*unaryNode)
+		return ok && a.op == b.op && equalNodes(a.operand, b.operand)
+	}
+	return false
+}
+
+// staticPath returns Path of variable if it has no index expression other than numbers.
+func (n *variableNode) staticPath() (Path, bool) {
+	path := make(Path, len(n.path))
+	for i, element := range n.path {
+		if element.index == nil {
+			path[i] = PathElement{Name: element.name}
+			continue
+		}
+		number, ok := element.index.(*numberNode)
+		if !ok {
+			return nil, false
+		}
+		index, ok := toIndex(number.value)
+		if !ok {
+			return nil, false
+		}
+		path[i] = PathElement{Index: index, IsIndex: true}
+	}
+	return path, true
+}
@@ -0,0 +1,196 @@
+package goculator
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// batchChunkSize is the number of rows evaluated at once by EvalBatch,
+// which keeps the columns of the stack in the CPU cache.
+const batchChunkSize = 256
+
+// EvalBatch evaluates Program for every row of columns and writes the results to out.
+// columns maps a variable name, or a path without index expressions like "items[0].price",
+// to the values of all rows, and each column must have at least len(out) values.
+// A quoted name and a path with the same key, e.g. `items[0]` and items[0], cannot be evaluated together,
+// because they would read the same column.
+//
+// Each operator is applied to a chunk of rows at once. The returned rowErrors has the error of each row
+// if Policy reports an error for some rows, and out of such a row is NaN. rowErrors is nil if no row fails.
+// err is returned if Program cannot be evaluated with columns at all, e.g. a column is missing.
+func (p *Program) EvalBatch(columns map[string][]float64, out []float64) (rowErrors []error, err error) {
+	if p.maxSteps > 0 && p.steps > p.maxSteps {
+		return nil, &StepLimitError{Max: p.maxSteps}
+	}
+
+	n := len(out)
+	// sources are the columns of opVar and opPath instructions.
+	sources := make([][]float64, len(p.code))
+	// paths has the keys of columns, which are read by a path if true and by a name if false.
+	paths := make(map[string]bool)
+	for i, in := range p.code {
+		var key string
+		switch in.op {
+		case opVar:
+			key = p.names[in.arg]
+		case opPath:
+			ref := &p.paths[in.arg]
+			if len(ref.dynamic) > 0 {
+				return nil, errors.New(fmt.Sprintf("variable '%s' with index expression is not supported in batch", ref.name))
+			}
+			key = ref.key
+		default:
+			continue
+		}
+
+		path := in.op == opPath
+		if seen, ok := paths[key]; ok && seen != path {
+			return nil, errors.New(fmt.Sprintf("variable '%s' and path '%s' have the same column", quoteVariable(key), key))
+		}
+		paths[key] = path
+
+		column, ok := columns[key]
+		if !ok {
+			return nil, errors.New(fmt.Sprintf("no column for variable '%s'", key))
+		}
+		if len(column) < n {
+			return nil, errors.New(fmt.Sprintf("column '%s' has %d rows, fewer than %d", key, len(column), n))
+		}
+		sources[i] = column
+	}
+
+	if len(p.code) == 0 {
+		for i := range out {
+			out[i] = 0
+		}
+		return nil, nil
+	}
+
+	b := batch{program: p, rows: n, sources: sources, stack: make([][]float64, p.stackSize)}
+	buffer := make([]float64, p.stackSize*batchChunkSize)
+	b.buffers = make([][]float64, p.stackSize)
+	for i := range b.buffers {
+		b.buffers[i] = buffer[i*batchChunkSize : (i+1)*batchChunkSize]
+	}
+
+	for start := 0; start < n; start += batchChunkSize {
+		end := start + batchChunkSize
+		if end > n {
+			end = n
+		}
+		b.run(start, end, out[start:end])
+	}
+
+	for i, err := range b.rowErrors {
+		if err != nil {
+			out[i] = math.NaN()
+		}
+	}
+	return b.rowErrors, nil
+}
+
+// EvalBatch compiles Expression and evaluates it for every row of columns. See Program.EvalBatch.
+func (e *Expression) EvalBatch(columns map[string][]float64, out []float64) ([]error, error) {
+	return e.Compile().EvalBatch(columns, out)
+}
+
+// batch is the state of EvalBatch.
+type batch struct {
+	program *Program
+	rows    int
+	sources [][]float64
+	// stack has a column for each depth, which is either a buffer or a column of sources.
+	stack   [][]float64
+	buffers [][]float64
+	// rowErrors is allocated when the first row fails.
+	rowErrors []error
+}
+
+// run evaluates rows from start to end and writes the results to out.
+func (b *batch) run(start, end int, out []float64) {
+	p := b.program
+	n := end - start
+	sp := 0
+	for i, in := range p.code {
+		switch in.op {
+		case opConst:
+			column := b.buffers[sp][:n]
+			value := p.constants[in.arg]
+			for j := range column {
+				column[j] = value
+			}
+			b.stack[sp] = column
+			sp++
+		case opVar, opPath:
+			// opPath has no dynamic indexes here, so it pops nothing.
+			column := b.sources[i][start:end]
+			if p.policy == PolicyErrorOnNonFinite {
+				var name string
+				if in.op == opVar {
+					name = p.names[in.arg]
+				} else {
+					name = p.paths[in.arg].name
+				}
+				for j, value := range column {
+					if !p.policy.allowsVariable(value) {
+						b.setError(start+j, variableError(name, value, p.positions[i]))
+					}
+				}
+			}
+			b.stack[sp] = column
+			sp++
+		case opAdd, opSub, opMul, opDiv:
+			sp--
+			left, right := b.stack[sp-1], b.stack[sp]
+			if p.policy != PolicyIEEE {
+				op := opcodeTokenTypes[in.op]
+				for j := range left {
+					b.setError(start+j, p.policy.checkBinary(op, left[j], right[j], binary(op, left[j], right[j]), p.positions[i]))
+				}
+			}
+
+			result := b.buffers[sp-1][:n]
+			switch in.op {
+			case opAdd:
+				for j := range result {
+					result[j] = left[j] + right[j]
+				}
+			case opSub:
+				for j := range result {
+					result[j] = left[j] - right[j]
+				}
+			case opMul:
+				for j := range result {
+					result[j] = left[j] * right[j]
+				}
+			case opDiv:
+				for j := range result {
+					result[j] = left[j] / right[j]
+				}
+			}
+			b.stack[sp-1] = result
+		case opNeg:
+			operand := b.stack[sp-1]
+			result := b.buffers[sp-1][:n]
+			for j := range result {
+				result[j] = -operand[j]
+			}
+			b.stack[sp-1] = result
+		}
+	}
+	copy(out, b.stack[0])
+}
+
+// setError sets err as the error of row if it is the first error of row.
+func (b *batch) setError(row int, err error) {
+	if err == nil {
+		return
+	}
+	if b.rowErrors == nil {
+		b.rowErrors = make([]error, b.rows)
+	}
+	if b.rowErrors[row] == nil {
+		b.rowErrors[row] = err
+	}
+}
@@ -0,0 +1,135 @@
+package goculator
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+func TestEvalBatch(t *testing.T) {
+	assert := assert.New(t)
+
+	var testdata = []string{
+		"",
+		"2.1-2*4/2+1",
+		"-(a + b) * 2",
+		"a / b - items[1].price",
+		"-a",
+	}
+	rows := 1000
+	columns := map[string][]float64{
+		"a":              make([]float64, rows),
+		"b":              make([]float64, rows),
+		"items[1].price": make([]float64, rows),
+	}
+	for i := 0; i < rows; i++ {
+		columns["a"][i] = float64(i) * 1.5
+		columns["b"][i] = float64(i%7) + 1
+		columns["items[1].price"][i] = float64(i % 13)
+	}
+
+	for _, input := range testdata {
+		expr, err := Parse(input)
+		if !assert.NoError(err, input) {
+			continue
+		}
+		out := make([]float64, rows)
+		rowErrors, err := expr.EvalBatch(columns, out)
+		assert.NoError(err, input)
+		assert.Nil(rowErrors, input)
+
+		for i := 0; i < rows; i++ {
+			context := NewDefaultContext(map[string]float64{
+				"a":              columns["a"][i],
+				"b":              columns["b"][i],
+				"items[1].price": columns["items[1].price"][i],
+			})
+			expected, _ := expr.Eval(context)
+			if !assert.Equal(expected, out[i], input) {
+				break
+			}
+		}
+	}
+}
+
+func TestEvalBatchError(t *testing.T) {
+	assert := assert.New(t)
+
+	columns := map[string][]float64{"a": {1, 2, 3}, "b": {1, 0, math.NaN()}}
+	out := make([]float64, 3)
+
+	expr, _ := Parse("a / b", WithPolicy(PolicyErrorOnDivisionByZero))
+	rowErrors, err := expr.EvalBatch(columns, out)
+	assert.NoError(err)
+	if assert.Len(rowErrors, 3) {
+		assert.NoError(rowErrors[0])
+		assert.EqualError(rowErrors[1], "division by zero: 2 / 0 at position 2")
+		assert.NoError(rowErrors[2])
+	}
+	assert.Equal(float64(1), out[0])
+	assert.True(math.IsNaN(out[1]))
+
+	expr, _ = Parse("a / b", WithPolicy(PolicyErrorOnNonFinite))
+	rowErrors, _ = expr.EvalBatch(columns, out)
+	assert.EqualError(rowErrors[2], "variable 'b' at position 4 is NaN")
+
+	expr, _ = Parse("a * c")
+	_, err = expr.EvalBatch(columns, out)
+	assert.EqualError(err, "no column for variable 'c'")
+
+	_, err = expr.EvalBatch(map[string][]float64{"a": {1}, "c": {1, 2}}, out)
+	assert.EqualError(err, "column 'a' has 1 rows, fewer than 3")
+
+	expr, _ = Parse("`items[0]` + items[0]")
+	_, err = expr.EvalBatch(map[string][]float64{"items[0]": {1, 2, 3}}, out)
+	assert.EqualError(err, "variable '`items[0]`' and path 'items[0]' have the same column")
+
+	expr, _ = Parse("items[a].price")
+	_, err = expr.EvalBatch(columns, out)
+	assert.EqualError(err, "variable 'items[a].price' with index expression is not supported in batch")
+}
+
+const benchmarkRows = 10000
+
+func benchmarkColumns() map[string][]float64 {
+	columns := make(map[string][]float64)
+	for name, value := range benchmarkValues {
+		column := make([]float64, benchmarkRows)
+		for i := range column {
+			column[i] = value + float64(i%100)
+		}
+		columns[name] = column
+	}
+	return columns
+}
+
+func BenchmarkEvalBatch(b *testing.B) {
+	columns := benchmarkColumns()
+	expr, _ := Parse(benchmarkInput)
+	program := expr.Compile()
+	out := make([]float64, benchmarkRows)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		program.EvalBatch(columns, out)
+	}
+}
+
+func BenchmarkEvalBatchRowByRow(b *testing.B) {
+	columns := benchmarkColumns()
+	out := make([]float64, benchmarkRows)
+	values := make(map[string]float64)
+	context := NewDefaultContext(values)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for row := range out {
+			for name, column := range columns {
+				values[name] = column[row]
+			}
+			calc := New(benchmarkInput)
+			calc.Bind(context)
+			out[row], _ = calc.Go()
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package goculator
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+)
+
+// Cache is an LRU cache of parsed and compiled expressions keyed by input and options.
+// Cache is safe for concurrent use.
+type Cache struct {
+	mu      sync.Mutex
+	options CacheOptions
+	entries map[cacheKey]*list.Element
+	// lru has *cacheEntry from the most recently used to the least recently used.
+	lru   *list.List
+	stats CacheStats
+}
+
+// CacheOptions are options of Cache.
+type CacheOptions struct {
+	// Size is the maximum number of entries. Zero or negative size means no limit.
+	Size int
+	// Negative caches errors of parsing too, so that invalid input is not parsed again.
+	Negative bool
+	// TrimSpace normalizes input by removing leading and trailing white space, so that inputs which differ
+	// only in such white space share an entry. Normalized input is parsed, so positions in errors and Spans
+	// are relative to it. Otherwise input is used as it is, and positions are the same as without Cache.
+	TrimSpace bool
+}
+
+// CacheStats is statistics of Cache.
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	// Len is the number of cached entries.
+	Len int
+}
+
+type cacheKey struct {
+	input   string
+	options options
+}
+
+type cacheEntry struct {
+	key     cacheKey
+	expr    *Expression
+	program *Program
+	err     error
+}
+
+// NewCache returns new Cache with options.
+func NewCache(options CacheOptions) *Cache {
+	return &Cache{options: options, entries: make(map[cacheKey]*list.Element), lru: list.New()}
+}
+
+// Parse returns cached Expression of input and opts, or parses and caches it like Parse.
+func (c *Cache) Parse(input string, opts ...Option) (*Expression, error) {
+	entry := c.get(input, opts)
+	return entry.expr, entry.err
+}
+
+// Compile returns cached Program of input and opts, or parses, compiles and caches it.
+func (c *Cache) Compile(input string, opts ...Option) (*Program, error) {
+	entry := c.get(input, opts)
+	return entry.program, entry.err
+}
+
+// New returns new Calculator of cached Expression like New. It is a drop-in replacement for New
+// unless TrimSpace of CacheOptions changes positions.
+func (c *Cache) New(input string, opts ...Option) *Calculator {
+	entry := c.get(input, opts)
+	return &Calculator{input: input, expr: entry.expr, err: entry.err}
+}
+
+// Stats returns statistics of Cache.
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Len = c.lru.Len()
+	return stats
+}
+
+// Purge removes all entries. Statistics are kept.
+func (c *Cache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[cacheKey]*list.Element)
+	c.lru.Init()
+}
+
+func (c *Cache) get(input string, opts []Option) *cacheEntry {
+	key := cacheKey{input: input, options: newOptions(opts)}
+	if c.options.TrimSpace {
+		key.input = strings.TrimSpace(input)
+	}
+
+	c.mu.Lock()
+	if element, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(element)
+		c.stats.Hits++
+		c.mu.Unlock()
+		return element.Value.(*cacheEntry)
+	}
+	c.stats.Misses++
+	c.mu.Unlock()
+
+	// Parsing is done without the lock, so the same input may be parsed by goroutines at the same time.
+	entry := &cacheEntry{key: key}
+	entry.expr, entry.err = Parse(key.input, opts...)
+	if entry.err == nil {
+		entry.program = entry.expr.Compile()
+	} else if !c.options.Negative {
+		return entry
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(element)
+		return element.Value.(*cacheEntry)
+	}
+	c.entries[key] = c.lru.PushFront(entry)
+	if c.options.Size > 0 && c.lru.Len() > c.options.Size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+		c.stats.Evictions++
+	}
+	return entry
+}
@@ -0,0 +1,144 @@
+package goculator
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+)
+
+func TestCache(t *testing.T) {
+	assert := assert.New(t)
+
+	cache := NewCache(CacheOptions{Size: 2})
+	expr1, err := cache.Parse("1 + x")
+	assert.NoError(err)
+	expr2, _ := cache.Parse("1 + x")
+	assert.True(expr1 == expr2)
+	assert.Equal(CacheStats{Hits: 1, Misses: 1, Len: 1}, cache.Stats())
+
+	// options are a part of the key
+	expr3, _ := cache.Parse("1 + x", WithPolicy(PolicyErrorOnNonFinite))
+	assert.False(expr1 == expr3)
+	expr4, _ := cache.Parse("1 + x", WithPolicy(PolicyErrorOnNonFinite))
+	assert.True(expr3 == expr4)
+
+	// "1 + x" is the least recently used
+	cache.Parse("2")
+	assert.Equal(CacheStats{Hits: 2, Misses: 3, Evictions: 1, Len: 2}, cache.Stats())
+	cache.Parse("1 + x")
+	assert.Equal(uint64(4), cache.Stats().Misses)
+
+	program, err := cache.Compile("2 * 3")
+	assert.NoError(err)
+	result, _ := program.Run(nil)
+	assert.Equal(float64(6), result)
+
+	calc := cache.New("x * 2")
+	calc.Bind(NewDefaultContext(map[string]float64{"x": 4}))
+	result, err = calc.Go()
+	assert.NoError(err)
+	assert.Equal(float64(8), result)
+
+	cache.Purge()
+	assert.Equal(0, cache.Stats().Len)
+}
+
+func TestCacheError(t *testing.T) {
+	assert := assert.New(t)
+
+	cache := NewCache(CacheOptions{Size: 10})
+	_, err := cache.Parse("1 +")
+	assert.Error(err)
+	_, err = cache.New("1 +").Go()
+	assert.Error(err)
+	assert.Equal(CacheStats{Misses: 2}, cache.Stats())
+
+	cache = NewCache(CacheOptions{Size: 10, Negative: true})
+	_, err1 := cache.Parse("1 +")
+	_, err2 := cache.Compile("1 +")
+	assert.Error(err1)
+	assert.True(err1 == err2)
+	assert.Equal(CacheStats{Hits: 1, Misses: 1, Len: 1}, cache.Stats())
+}
+
+func TestCacheSameAsNew(t *testing.T) {
+	assert := assert.New(t)
+
+	cache := NewCache(CacheOptions{Size: 10, Negative: true})
+	for _, input := range []string{"   1 + $", "1 + $", "1 +   ", "1 +", "\t(x", " x * y "} {
+		_, expected := New(input).Go()
+		for i := 0; i < 2; i++ {
+			_, err := cache.New(input).Go()
+			if assert.Error(err, input) {
+				assert.Equal(expected.Error(), err.Error(), input)
+			}
+		}
+	}
+
+	expr, _ := cache.Parse("  x * y")
+	assert.Equal(Position{Offset: 2, Rune: 2}, expr.Variables()[0].Span.Start)
+	assert.Equal(CacheStats{Hits: 6, Misses: 7, Len: 7}, cache.Stats())
+}
+
+func TestCacheTrimSpace(t *testing.T) {
+	assert := assert.New(t)
+
+	cache := NewCache(CacheOptions{TrimSpace: true, Negative: true})
+	expr1, err := cache.Parse("x * y")
+	assert.NoError(err)
+	expr2, err := cache.Parse("  x * y\n")
+	assert.NoError(err)
+	assert.True(expr1 == expr2)
+	// positions are relative to the trimmed input
+	assert.Equal(Position{Offset: 0, Rune: 0}, expr2.Variables()[0].Span.Start)
+
+	_, err1 := cache.New("1 +").Go()
+	_, err2 := cache.New("\t1 +  ").Go()
+	assert.Error(err1)
+	assert.True(err1 == err2)
+	assert.Equal(CacheStats{Hits: 2, Misses: 2, Len: 2}, cache.Stats())
+
+	// without TrimSpace, white space makes another entry
+	cache = NewCache(CacheOptions{})
+	expr1, _ = cache.Parse("x * y")
+	expr2, _ = cache.Parse("  x * y\n")
+	assert.False(expr1 == expr2)
+	assert.Equal(2, cache.Stats().Len)
+}
+
+func TestCacheConcurrent(t *testing.T) {
+	assert := assert.New(t)
+
+	cache := NewCache(CacheOptions{Size: 8, Negative: true})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				calc := cache.New(fmt.Sprintf("%d * x", (i+j)%16))
+				calc.Bind(NewDefaultContext(map[string]float64{"x": 2}))
+				result, err := calc.Go()
+				assert.NoError(err)
+				assert.Equal(float64((i+j)%16*2), result)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	stats := cache.Stats()
+	assert.Equal(uint64(8000), stats.Hits+stats.Misses)
+	assert.Equal(8, stats.Len)
+}
+
+func BenchmarkCacheNew(b *testing.B) {
+	cache := NewCache(CacheOptions{Size: 16})
+	context := NewDefaultContext(benchmarkValues)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		calc := cache.New(benchmarkInput)
+		calc.Bind(context)
+		calc.Go()
+	}
+}
@@ -1,8 +1,12 @@
 package goculator
 
 import (
+	"context"
+	"fmt"
 	"github.com/stretchr/testify/assert"
 	"math"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -37,6 +41,22 @@ func TestCalculator(t *testing.T) {
 			"",
 			0,
 		},
+		{
+			"0xFF + 0b1010 + 0o17 ",
+			280,
+		},
+		{
+			"1_000 * 1e-3 + 2.5E2",
+			251,
+		},
+		{
+			"-2 * -(3 + 1) - +1",
+			7,
+		},
+		{
+			"# margin\n(32 + 34)\t/ /* months */ 11 // per month\n",
+			6,
+		},
 	}
 
 	for _, data := range testdata {
@@ -68,12 +88,23 @@ func TestCalculatorWithContext(t *testing.T) {
 			"2.1/(var1 + var2)",
 			0.33,
 		},
+		{
+			"2 × π × größe ÷ (var1 − var2)",
+			-2.99,
+		},
+		{
+			"`Unit Price (USD)` * 2",
+			3,
+		},
 	}
 
 	context := NewDefaultContext(
 		map[string]float64{
-			"var1": 2.1,
-			"var2": 4.2,
+			"var1":             2.1,
+			"var2":             4.2,
+			"π":                3.14159,
+			"größe":            1,
+			"Unit Price (USD)": 1.5,
 		},
 	)
 
@@ -96,3 +127,187 @@ func TestCalculatorWithContext(t *testing.T) {
 
 	}
 }
+
+func TestCalculatorInvalidNumber(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := New("1.2.3 + 1").Go()
+	assert.EqualError(err, "invalid number '1.2.3' at position 0")
+
+	_, err = New("1e999").Go()
+	assert.EqualError(err, "number '1e999' is out of range at position 0")
+
+	hex := "0x1" + strings.Repeat("0", 300)
+	_, err = New("2 * " + hex).Go()
+	assert.EqualError(err, fmt.Sprintf("number '%s' is out of range at position 4", hex))
+
+	_, err = New("0b1" + strings.Repeat("0", 1024)).Go()
+	assert.Error(err)
+
+	result, err := New("0b1" + strings.Repeat("0", 1023)).Go()
+	assert.NoError(err)
+	assert.Equal(math.Ldexp(1, 1023), result)
+}
+
+func TestCalculatorWithPath(t *testing.T) {
+	assert := assert.New(t)
+	type item struct {
+		Price float64
+		Qty   int
+	}
+	var testdata = []struct {
+		input  string
+		result float64
+	}{
+		{
+			"order.customer.tier * 10",
+			20,
+		},
+		{
+			"items[1].Price * items[1].Qty + items[0].Price",
+			7,
+		},
+		{
+			"items[order.customer.tier - 1].Qty",
+			3,
+		},
+		{
+			"order.`unit price`",
+			1.5,
+		},
+	}
+
+	context := NewNestedContext(
+		map[string]interface{}{
+			"order": map[string]interface{}{
+				"customer": map[string]int{
+					"tier": 2,
+				},
+				"unit price": 1.5,
+			},
+			"items": []*item{
+				&item{Price: 1, Qty: 1},
+				&item{Price: 2, Qty: 3},
+			},
+		},
+	)
+
+	for _, data := range testdata {
+		calc := New(data.input)
+		calc.Bind(context)
+
+		result, err := calc.Go()
+
+		if err != nil {
+			assert.Fail(err.Error())
+			return
+		}
+
+		assert.InDelta(data.result, result, 0.01)
+	}
+
+	_, err := goWith(New("items[2].Price"), context)
+	assert.EqualError(err, "index out of range for 'items[2]'")
+
+	_, err = goWith(New("items[0.5].Price"), context)
+	assert.EqualError(err, "index 0.5 of 'items' is not a non-negative integer")
+
+	_, err = goWith(New("order.customer.rank"), context)
+	assert.EqualError(err, "no value for key 'order.customer.rank'")
+}
+
+func TestCalculatorWithPathFallback(t *testing.T) {
+	assert := assert.New(t)
+
+	context := NewDefaultContext(
+		map[string]float64{
+			"order.customer.tier": 2,
+			"items[1].price":      3,
+			"a.`b c`":             4,
+		},
+	)
+
+	result, err := goWith(New("order.customer.tier * items[1].price + a.`b c`"), context)
+	assert.NoError(err)
+	assert.Equal(float64(10), result)
+}
+
+func goWith(c *Calculator, context Context) (float64, error) {
+	c.Bind(context)
+	return c.Go()
+}
+
+func TestCalculatorWithLocale(t *testing.T) {
+	assert := assert.New(t)
+
+	result, err := New("1.234,5 * 2 + 0,25", WithLocale(DecimalCommaLocale)).Go()
+	assert.NoError(err)
+	assert.Equal(2469.25, result)
+}
+
+func TestCalculatorConcurrent(t *testing.T) {
+	assert := assert.New(t)
+
+	calc := New("items[i].price * qty + qty")
+	contexts := make([]Context, 4)
+	for i := range contexts {
+		contexts[i] = NewNestedContext(map[string]interface{}{
+			"i": i % 2, "qty": i,
+			"items": []map[string]float64{{"price": 10}, {"price": 20}},
+		})
+	}
+	expected := []float64{0, 21, 22, 63}
+	calc.Bind(contexts[0])
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for j := 0; j < 500; j++ {
+				i := (g + j) % len(contexts)
+				result, err := calc.GoWith(contexts[i])
+				assert.NoError(err)
+				assert.Equal(expected[i], result)
+
+				// Go uses one of the contexts which are bound by goroutines.
+				calc.Bind(contexts[i])
+				result, err = calc.Go()
+				assert.NoError(err)
+				assert.Contains(expected, result)
+
+				result, err = calc.GoContext(context.Background())
+				assert.NoError(err)
+				assert.Contains(expected, result)
+			}
+		}(g)
+	}
+	wg.Wait()
+}
+
+func TestExpressionConcurrent(t *testing.T) {
+	assert := assert.New(t)
+
+	expr, _ := Parse(benchmarkInput)
+	program := expr.Compile()
+	closure := expr.CompileClosure()
+	context := NewDefaultContext(benchmarkValues)
+	expected, _ := expr.Eval(context)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 500; j++ {
+				result, _ := expr.Eval(context)
+				assert.Equal(expected, result)
+				result, _ = program.Run(context)
+				assert.Equal(expected, result)
+				result, _ = closure.Run(context)
+				assert.Equal(expected, result)
+			}
+		}()
+	}
+	wg.Wait()
+}
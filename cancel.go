@@ -0,0 +1,78 @@
+package goculator
+
+import "context"
+
+// CancelableContext is Context whose variable lookup receives context.Context,
+// so that a slow lookup can be canceled or given a deadline.
+// GoContext, EvalContext and RunContext call ValueContext instead of Value.
+type CancelableContext interface {
+	Context
+	ValueContext(ctx context.Context, key string) (float64, error)
+}
+
+// CancelablePathContext is PathContext whose path lookup receives context.Context,
+// so that a slow lookup of nested data can be canceled or given a deadline.
+// GoContext, EvalContext and RunContext call PathValueContext instead of PathValue.
+type CancelablePathContext interface {
+	PathContext
+	PathValueContext(ctx context.Context, path Path) (float64, error)
+}
+
+// cancelCheckInterval is the number of evaluation steps between checks of cancellation.
+const cancelCheckInterval = 64
+
+// GoContext is Go which stops with the error of ctx when ctx is canceled or its deadline is exceeded.
+func (c *Calculator) GoContext(ctx context.Context) (float64, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.expr.EvalContext(ctx, c.bound())
+}
+
+// EvalContext is Eval which stops with the error of ctx when ctx is canceled or its deadline is exceeded.
+func (e *Expression) EvalContext(ctx context.Context, vars Context) (float64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return e.evaluate(ctx, vars)
+}
+
+// RunContext is Run which stops with the error of ctx when ctx is canceled or its deadline is exceeded.
+func (p *Program) RunContext(ctx context.Context, vars Context) (float64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return p.run(ctx, vars)
+}
+
+// valueContext looks up key in vars with ctx if vars is CancelableContext and ctx is not nil.
+func valueContext(ctx context.Context, vars Context, key string) (float64, error) {
+	if ctx == nil {
+		return value(vars, key)
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if vars, ok := vars.(CancelableContext); ok {
+		return vars.ValueContext(ctx, key)
+	}
+	return value(vars, key)
+}
+
+// pathValueContext looks up path in vars with ctx if vars is CancelablePathContext and ctx is not nil.
+// The string of path is looked up like valueContext if vars is not PathContext.
+func pathValueContext(ctx context.Context, vars Context, path Path) (float64, error) {
+	if ctx == nil {
+		return pathValue(vars, path)
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	switch vars := vars.(type) {
+	case CancelablePathContext:
+		return vars.PathValueContext(ctx, path)
+	case PathContext:
+		return vars.PathValue(path)
+	}
+	return valueContext(ctx, vars, path.String())
+}
@@ -0,0 +1,154 @@
+package goculator
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+// slowContext waits for delay in ValueContext unless ctx is done first.
+type slowContext struct {
+	delay time.Duration
+}
+
+func (c *slowContext) Value(key string) (float64, error) {
+	time.Sleep(c.delay)
+	return 1, nil
+}
+
+func (c *slowContext) ValueContext(ctx context.Context, key string) (float64, error) {
+	select {
+	case <-time.After(c.delay):
+		return 1, nil
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+// cancelingContext cancels after a number of lookups.
+type cancelingContext struct {
+	cancel  context.CancelFunc
+	lookups int
+}
+
+func (c *cancelingContext) Value(key string) (float64, error) {
+	c.lookups++
+	if c.lookups == 3 {
+		c.cancel()
+	}
+	return 1, nil
+}
+
+// cancelingPathContext is PathContext which cancels at its first lookup.
+type cancelingPathContext struct {
+	cancel  context.CancelFunc
+	lookups int
+}
+
+func (c *cancelingPathContext) Value(key string) (float64, error) {
+	return c.PathValue(Path{PathElement{Name: key}})
+}
+
+func (c *cancelingPathContext) PathValue(path Path) (float64, error) {
+	c.lookups++
+	c.cancel()
+	return 1, nil
+}
+
+// slowPathContext waits for delay in PathValueContext unless ctx is done first.
+type slowPathContext struct {
+	slowContext
+}
+
+func (c *slowPathContext) PathValue(path Path) (float64, error) {
+	return c.Value(path.String())
+}
+
+func (c *slowPathContext) PathValueContext(ctx context.Context, path Path) (float64, error) {
+	return c.ValueContext(ctx, path.String())
+}
+
+func TestGoContext(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	calc := New("price * qty")
+	calc.Bind(&slowContext{delay: time.Minute})
+	start := time.Now()
+	_, err := calc.GoContext(ctx)
+	assert.Equal(context.DeadlineExceeded, err)
+	assert.True(time.Since(start) < time.Minute)
+
+	calc.Bind(&slowContext{delay: 0})
+	result, err := calc.GoContext(context.Background())
+	assert.NoError(err)
+	assert.Equal(float64(1), result)
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = New("1 + 2").GoContext(canceled)
+	assert.Equal(context.Canceled, err)
+}
+
+func TestEvalContextCancelDuringEvaluation(t *testing.T) {
+	assert := assert.New(t)
+
+	expr, err := Parse(strings.Repeat("x + ", 1000) + "x")
+	assert.NoError(err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	vars := &cancelingContext{cancel: cancel}
+	_, err = expr.EvalContext(ctx, vars)
+	assert.Equal(context.Canceled, err)
+	assert.Equal(3, vars.lookups)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	vars = &cancelingContext{cancel: cancel}
+	_, err = expr.Compile().RunContext(ctx, vars)
+	assert.Equal(context.Canceled, err)
+	assert.Equal(3, vars.lookups)
+
+	set := NewFormulaSet()
+	set.Add("a", "x + x")
+	set.Add("b", "a + x + x")
+	ctx, cancel = context.WithCancel(context.Background())
+	_, err = set.EvaluateContext(ctx, &cancelingContext{cancel: cancel})
+	assert.Equal(context.Canceled, err)
+}
+
+func TestEvalContextPathContext(t *testing.T) {
+	assert := assert.New(t)
+
+	expr, err := Parse("a.b + c[0] + d")
+	assert.NoError(err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	vars := &cancelingPathContext{cancel: cancel}
+	_, err = expr.EvalContext(ctx, vars)
+	assert.Equal(context.Canceled, err)
+	assert.Equal(1, vars.lookups)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	vars = &cancelingPathContext{cancel: cancel}
+	_, err = expr.Compile().RunContext(ctx, vars)
+	assert.Equal(context.Canceled, err)
+	assert.Equal(1, vars.lookups)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = expr.Compile().RunContext(ctx, &slowPathContext{slowContext{delay: time.Minute}})
+	assert.Equal(context.DeadlineExceeded, err)
+	assert.True(time.Since(start) < time.Minute)
+
+	set := NewFormulaSet()
+	set.Add("total", "a.b * 2")
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = set.EvaluateContext(ctx, &slowPathContext{slowContext{delay: time.Minute}})
+	assert.Equal(context.DeadlineExceeded, err)
+}
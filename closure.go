@@ -0,0 +1,245 @@
+package goculator
+
+import (
+	"errors"
+	"fmt"
+)
+
+// closureFunc evaluates a node with the values of slots. vars is used only for variables with index expressions.
+type closureFunc func(slots []float64, vars Context) (float64, error)
+
+// Closure is Expression compiled to nested Go closures. Variables are resolved to slot indexes
+// when Expression is compiled, so evaluation does not look up names.
+// Closure is immutable and safe for concurrent use.
+type Closure struct {
+	fn closureFunc
+	// slots are the paths of variables in the order of slot indexes, and keys are their keys.
+	slots []Path
+	keys  []string
+	// steps is the number of nodes, each of which is evaluated once.
+	steps int
+	// maxSteps is MaxSteps of Limits.
+	maxSteps int
+}
+
+// CompileClosure compiles Expression to Closure.
+func (e *Expression) CompileClosure() *Closure {
+	c := &closureCompiler{closure: &Closure{maxSteps: e.options.limits.MaxSteps}, policy: e.options.policy, slotIndex: make(map[slotKey]int)}
+	if e.root == nil {
+		c.closure.fn = func([]float64, Context) (float64, error) { return 0, nil }
+		return c.closure
+	}
+	c.closure.fn = c.compile(e.root)
+	return c.closure
+}
+
+// Slots returns the keys of variables in the order of slot indexes, which Call takes values of.
+// A variable with index expressions has no slot and is looked up from Context by Run.
+// A quoted name and a path can have the same key, e.g. `items[0]` and items[0], but they have separate slots.
+func (c *Closure) Slots() []string {
+	keys := make([]string, len(c.keys))
+	copy(keys, c.keys)
+	return keys
+}
+
+// Call evaluates Closure with the values of slots in the order of Slots.
+func (c *Closure) Call(slots []float64) (float64, error) {
+	if len(slots) != len(c.keys) {
+		return 0, errors.New(fmt.Sprintf("expected %d slot values, got %d", len(c.keys), len(slots)))
+	}
+	if c.maxSteps > 0 && c.steps > c.maxSteps {
+		return 0, &StepLimitError{Max: c.maxSteps}
+	}
+	return c.call(slots, nil)
+}
+
+// Run looks up the values of slots from context and evaluates Closure.
+// context can be nil if Expression has no variable.
+func (c *Closure) Run(context Context) (float64, error) {
+	if c.maxSteps > 0 && c.steps > c.maxSteps {
+		return 0, &StepLimitError{Max: c.maxSteps}
+	}
+
+	slots := make([]float64, len(c.slots))
+	for i, path := range c.slots {
+		var err error
+		if len(path) == 1 {
+			slots[i], err = value(context, c.keys[i])
+		} else {
+			slots[i], err = pathValue(context, path)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	return c.call(slots, context)
+}
+
+func (c *Closure) call(slots []float64, context Context) (float64, error) {
+	result, err := c.fn(slots, context)
+	if err != nil {
+		return 0, err
+	}
+	return result, nil
+}
+
+type closureCompiler struct {
+	closure   *Closure
+	policy    Policy
+	slotIndex map[slotKey]int
+}
+
+// slotKey identifies the variable of a slot. The key of a single name is not enough,
+// because it can be the same as the key of a path, which is looked up by PathValue.
+type slotKey struct {
+	key  string
+	path bool
+}
+
+func (c *closureCompiler) compile(n node) closureFunc {
+	c.closure.steps++
+	switch n := n.(type) {
+	case *numberNode:
+		value := n.value
+		return func([]float64, Context) (float64, error) { return value, nil }
+	case *variableNode:
+		return c.compileVariable(n)
+	case *binaryNode:
+		return c.compileBinary(n)
+	case *unaryNode:
+		operand := c.compile(n.operand)
+		if n.op != TokenTypeMINUS {
+			return operand
+		}
+		return func(slots []float64, vars Context) (float64, error) {
+			result, err := operand(slots, vars)
+			return -result, err
+		}
+	}
+	panic(fmt.Sprintf("unknown node %T", n))
+}
+
+func (c *closureCompiler) compileVariable(n *variableNode) closureFunc {
+	policy, pos := c.policy, n.s.Start
+	path, ok := n.staticPath()
+	if !ok {
+		// The path has index expressions which are evaluated by closures.
+		indexes := make([]closureFunc, len(n.path))
+		for i, element := range n.path {
+			if element.index != nil {
+				indexes[i] = c.compile(element.index)
+			}
+		}
+		return func(slots []float64, vars Context) (float64, error) {
+			path := make(Path, len(n.path))
+			for i, element := range n.path {
+				if indexes[i] == nil {
+					path[i] = PathElement{Name: element.name}
+					continue
+				}
+				result, err := indexes[i](slots, vars)
+				if err != nil {
+					return 0, err
+				}
+				index, ok := toIndex(result)
+				if !ok {
+					return 0, errors.New(fmt.Sprintf("index %v of '%s' is not a non-negative integer", result, path[:i]))
+				}
+				path[i] = PathElement{Index: index, IsIndex: true}
+			}
+			result, err := pathValue(vars, path)
+			if err != nil {
+				return 0, err
+			}
+			if !policy.allowsVariable(result) {
+				return 0, variableError(variableKey(n), result, pos)
+			}
+			return result, nil
+		}
+	}
+
+	// Number literal indexes are part of the static path, but still count as steps.
+	for _, element := range n.path {
+		if element.index != nil {
+			c.closure.steps++
+		}
+	}
+	name := variableKey(n)
+	key := slotKey{key: name, path: len(path) > 1}
+	index, ok := c.slotIndex[key]
+	if !ok {
+		index = len(c.closure.keys)
+		c.slotIndex[key] = index
+		c.closure.keys = append(c.closure.keys, name)
+		c.closure.slots = append(c.closure.slots, path)
+	}
+	if policy == PolicyErrorOnNonFinite {
+		return func(slots []float64, _ Context) (float64, error) {
+			if !policy.allowsVariable(slots[index]) {
+				return 0, variableError(name, slots[index], pos)
+			}
+			return slots[index], nil
+		}
+	}
+	return func(slots []float64, _ Context) (float64, error) { return slots[index], nil }
+}
+
+func (c *closureCompiler) compileBinary(n *binaryNode) closureFunc {
+	left, right := c.compile(n.left), c.compile(n.right)
+	if c.policy != PolicyIEEE {
+		policy, op, pos := c.policy, n.op, n.opPos
+		return func(slots []float64, vars Context) (float64, error) {
+			l, err := left(slots, vars)
+			if err != nil {
+				return 0, err
+			}
+			r, err := right(slots, vars)
+			if err != nil {
+				return 0, err
+			}
+			result := binary(op, l, r)
+			return result, policy.checkBinary(op, l, r, result, pos)
+		}
+	}
+
+	// Each operator has its own closure to avoid switching on the operator in evaluation.
+	switch n.op {
+	case TokenTypePLUS:
+		return func(slots []float64, vars Context) (float64, error) {
+			l, err := left(slots, vars)
+			if err != nil {
+				return 0, err
+			}
+			r, err := right(slots, vars)
+			return l + r, err
+		}
+	case TokenTypeMINUS:
+		return func(slots []float64, vars Context) (float64, error) {
+			l, err := left(slots, vars)
+			if err != nil {
+				return 0, err
+			}
+			r, err := right(slots, vars)
+			return l - r, err
+		}
+	case TokenTypeMULTI:
+		return func(slots []float64, vars Context) (float64, error) {
+			l, err := left(slots, vars)
+			if err != nil {
+				return 0, err
+			}
+			r, err := right(slots, vars)
+			return l * r, err
+		}
+	case TokenTypeDIV:
+		return func(slots []float64, vars Context) (float64, error) {
+			l, err := left(slots, vars)
+			if err != nil {
+				return 0, err
+			}
+			r, err := right(slots, vars)
+			return l / r, err
+		}
+	}
+	panic(fmt.Sprintf("unknown operator %s", n.op))
+}
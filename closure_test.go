@@ -0,0 +1,103 @@
+package goculator
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestClosure(t *testing.T) {
+	assert := assert.New(t)
+	var testdata = []string{
+		"",
+		"32+21.1-21",
+		"2.1-2*4/2+1",
+		"2.1/(var1 + var2)",
+		"-(var1 - -var2) * +var1",
+		"var1 * var1 / items[1].price",
+		"items[var1 - 2].price + var2",
+	}
+	context := NewNestedContext(map[string]interface{}{
+		"var1": 3, "var2": 4.5,
+		"items": []map[string]float64{{"price": 10}, {"price": 20}},
+	})
+
+	for _, input := range testdata {
+		expr, err := Parse(input)
+		if !assert.NoError(err, input) {
+			continue
+		}
+		expected, err := expr.Eval(context)
+		assert.NoError(err, input)
+
+		result, err := expr.CompileClosure().Run(context)
+		assert.NoError(err, input)
+		assert.Equal(expected, result, input)
+	}
+
+	expr, _ := Parse("(price - cost) * qty / price")
+	closure := expr.CompileClosure()
+	assert.Equal([]string{"price", "cost", "qty"}, closure.Slots())
+	result, err := closure.Call([]float64{10, 4, 5})
+	assert.NoError(err)
+	assert.Equal(float64(3), result)
+
+	_, err = closure.Call([]float64{10})
+	assert.EqualError(err, "expected 3 slot values, got 1")
+	_, err = closure.Run(NewDefaultContext(map[string]float64{"price": 10}))
+	assert.EqualError(err, "no value for key 'cost'")
+
+	// a quoted name and a path with the same key have separate slots
+	expr, _ = Parse("`items[0]` + items[0] * 10")
+	closure = expr.CompileClosure()
+	assert.Equal([]string{"items[0]", "items[0]"}, closure.Slots())
+	result, err = closure.Run(&keyOrPathContext{})
+	assert.NoError(err)
+	assert.Equal(float64(21), result)
+	result, err = closure.Call([]float64{1, 2})
+	assert.NoError(err)
+	assert.Equal(float64(21), result)
+
+	expr, _ = Parse("1 / (x - 1)", WithPolicy(PolicyErrorOnDivisionByZero))
+	_, err = expr.CompileClosure().Call([]float64{1})
+	assert.EqualError(err, "division by zero: 1 / 0 at position 2")
+
+	expr, _ = Parse("1 + x * 2", WithLimits(Limits{MaxSteps: 4}))
+	_, err = expr.CompileClosure().Call([]float64{1})
+	assert.IsType(&StepLimitError{}, err)
+}
+
+// keyOrPathContext returns 1 for any key and 2 for any path.
+type keyOrPathContext struct{}
+
+func (c *keyOrPathContext) Value(key string) (float64, error) {
+	return 1, nil
+}
+
+func (c *keyOrPathContext) PathValue(path Path) (float64, error) {
+	return 2, nil
+}
+
+func BenchmarkClosureRun(b *testing.B) {
+	context := NewDefaultContext(benchmarkValues)
+	expr, _ := Parse(benchmarkInput)
+	closure := expr.CompileClosure()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		closure.Run(context)
+	}
+}
+
+func BenchmarkClosureCall(b *testing.B) {
+	expr, _ := Parse(benchmarkInput)
+	closure := expr.CompileClosure()
+	slots := make([]float64, len(closure.Slots()))
+	for i, key := range closure.Slots() {
+		slots[i] = benchmarkValues[key]
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		closure.Call(slots)
+	}
+}
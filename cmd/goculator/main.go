@@ -0,0 +1,103 @@
+// Command goculator generates Go code from formulas.
+//
+// Usage:
+//
+//	goculator gen [-package name] [-o output.go] [-test=false] formulas-file
+//
+// gen reads formulas of the form "name = formula" from formulas-file and writes a Go function for each formula
+// to output.go, and a test which checks the functions against the interpreter to output_test.go.
+// It can be used with go:generate, e.g.
+//
+//	//go:generate goculator gen pricing.formulas
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/yhmin84/goculator"
+)
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] != "gen" {
+		fmt.Fprintln(os.Stderr, "usage: goculator gen [-package name] [-o output.go] [-test=false] formulas-file")
+		os.Exit(2)
+	}
+	if err := gen(os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "goculator gen: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func gen(args []string) error {
+	flags := flag.NewFlagSet("gen", flag.ExitOnError)
+	pkg := flags.String("package", os.Getenv("GOPACKAGE"), "package name of generated code (default $GOPACKAGE)")
+	output := flags.String("o", "", "output file (default formulas-file with .go extension)")
+	test := flags.Bool("test", true, "generate test file")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("expected one formulas file, got %d", flags.NArg())
+	}
+	if *pkg == "" {
+		return fmt.Errorf("-package is required unless run by go generate")
+	}
+
+	input := flags.Arg(0)
+	if *output == "" {
+		*output = strings.TrimSuffix(input, filepath.Ext(input)) + ".go"
+	}
+
+	file, err := os.Open(input)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	set, err := goculator.ReadFormulas(file)
+	if err != nil {
+		return fmt.Errorf("%s: %s", input, err)
+	}
+
+	base := strings.TrimSuffix(filepath.Base(*output), ".go")
+	options := goculator.GenerateOptions{Package: *pkg, Source: filepath.Base(input), TestName: "Test" + camelCase(base)}
+	source, err := goculator.GenerateGo(set, options)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(*output, source, 0644); err != nil {
+		return err
+	}
+
+	if !*test {
+		return nil
+	}
+	source, err = goculator.GenerateGoTest(set, options)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(strings.TrimSuffix(*output, ".go")+"_test.go", source, 0644)
+}
+
+// camelCase returns s without characters which are not letters or digits, and the words in upper camel case,
+// e.g. "Pricing" for "pricing" and "PriceRules" for "price_rules".
+func camelCase(s string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -1,11 +1,14 @@
 package goculator
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"reflect"
+	"strconv"
 )
 
-//Stringer is implemented by any value that has a Value method, which returns value of variable name.
+// Stringer is implemented by any value that has a Value method, which returns value of variable name.
 type Context interface {
 	Value(string) (float64, error)
 }
@@ -30,3 +33,99 @@ func (c *DefaultContext) Value(key string) (float64, error) {
 	}
 	return value, nil
 }
+
+// PathElement is an element of Path. It is either a member name or an index.
+type PathElement struct {
+	Name    string
+	Index   int
+	IsIndex bool
+}
+
+// Path is a variable with member access and indexing such as order.customer.tier or items[2].price.
+// The first element of Path is always a name.
+type Path []PathElement
+
+// String returns Path as it is written in the expression, e.g. "items[2].price".
+func (p Path) String() string {
+	var buf bytes.Buffer
+	for i, element := range p {
+		if element.IsIndex {
+			buf.WriteString("[" + strconv.Itoa(element.Index) + "]")
+			continue
+		}
+		if i > 0 {
+			buf.WriteString(".")
+		}
+		buf.WriteString(quoteVariable(element.Name))
+	}
+	return buf.String()
+}
+
+// PathContext is Context which receives structured Path of variables with member access or indexing.
+// Calculator calls PathValue for such variables and Value for plain variables.
+// If the bound Context is not PathContext, Value is called with the string of Path.
+// PathValue must not modify Path.
+type PathContext interface {
+	Context
+	PathValue(Path) (float64, error)
+}
+
+// NestedContext is PathContext which walks nested maps with string keys, slices, arrays and structs.
+type NestedContext struct {
+	data interface{}
+}
+
+// NewNestedContext returns new NestedContext with data. Values at the end of paths should be numbers.
+func NewNestedContext(data interface{}) *NestedContext {
+	c := new(NestedContext)
+	c.data = data
+	return c
+}
+
+// Value returns float64 value from key of top level data.
+func (c *NestedContext) Value(key string) (float64, error) {
+	return c.PathValue(Path{PathElement{Name: key}})
+}
+
+// PathValue returns float64 value found by walking path.
+func (c *NestedContext) PathValue(path Path) (float64, error) {
+	value := reflect.ValueOf(c.data)
+	for i, element := range path {
+		value = indirect(value)
+		switch {
+		case element.IsIndex && (value.Kind() == reflect.Slice || value.Kind() == reflect.Array):
+			if element.Index < 0 || value.Len() <= element.Index {
+				return 0, errors.New(fmt.Sprintf("index out of range for '%s'", path[:i+1]))
+			}
+			value = value.Index(element.Index)
+		case !element.IsIndex && value.Kind() == reflect.Map && value.Type().Key().Kind() == reflect.String:
+			value = value.MapIndex(reflect.ValueOf(element.Name).Convert(value.Type().Key()))
+		case !element.IsIndex && value.Kind() == reflect.Struct:
+			value = value.FieldByName(element.Name)
+		default:
+			return 0, errors.New(fmt.Sprintf("no value for key '%s'", path[:i+1]))
+		}
+		if !value.IsValid() {
+			return 0, errors.New(fmt.Sprintf("no value for key '%s'", path[:i+1]))
+		}
+	}
+
+	value = indirect(value)
+	switch value.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(value.Uint()), nil
+	}
+	return 0, errors.New(fmt.Sprintf("value for key '%s' is not a number", path))
+}
+
+// indirect dereferences pointers and interfaces.
+func indirect(value reflect.Value) reflect.Value {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		value = value.Elem()
+	}
+	return value
+}
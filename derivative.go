@@ -0,0 +1,111 @@
+package goculator
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Derivative returns the derivative of expr with respect to variable, built with the sum, product and quotient rules.
+// variable is the name of a plain variable like "x", or the path of a variable like "order.price" or "items[2].price".
+// The result is simplified with fast-math, so for example d(x * y)/dx is y, not 1 * y + x * 0.
+func Derivative(expr *Expression, variable string) (*Expression, error) {
+	result := &Expression{options: expr.options}
+	if expr.root == nil {
+		result.root = newConstantNode(0, Span{})
+		return result, nil
+	}
+
+	d := differentiator{variable: variable, simplifier: simplifier{fastMath: true}}
+	root, err := d.derivative(expr.root)
+	if err != nil {
+		return nil, err
+	}
+	result.root = root
+	return result.Simplify(true), nil
+}
+
+type differentiator struct {
+	variable   string
+	simplifier simplifier
+}
+
+func (d *differentiator) derivative(n node) (node, error) {
+	switch n := n.(type) {
+	case *numberNode:
+		return newConstantNode(0, n.s), nil
+	case *variableNode:
+		return d.variableDerivative(n)
+	case *unaryNode:
+		operand, err := d.derivative(n.operand)
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op: n.op, operand: operand, s: n.s}, nil
+	case *binaryNode:
+		left, err := d.derivative(n.left)
+		if err != nil {
+			return nil, err
+		}
+		right, err := d.derivative(n.right)
+		if err != nil {
+			return nil, err
+		}
+
+		switch n.op {
+		case TokenTypePLUS, TokenTypeMINUS:
+			// (u ± v)' = u' ± v'
+			return &binaryNode{op: n.op, left: left, right: right, s: n.s, opPos: n.opPos}, nil
+		case TokenTypeMULTI:
+			// (u * v)' = u' * v + u * v'
+			return &binaryNode{
+				op:    TokenTypePLUS,
+				left:  &binaryNode{op: TokenTypeMULTI, left: left, right: n.right, s: n.s},
+				right: &binaryNode{op: TokenTypeMULTI, left: n.left, right: right, s: n.s},
+				s:     n.s,
+			}, nil
+		case TokenTypeDIV:
+			// (u / c)' = u' / c
+			if value, ok := constantValue(d.simplifier.simplify(right)); ok && value == 0 {
+				return &binaryNode{op: TokenTypeDIV, left: left, right: n.right, s: n.s}, nil
+			}
+			// (u / v)' = (u' * v - u * v') / (v * v)
+			return &binaryNode{
+				op: TokenTypeDIV,
+				left: &binaryNode{
+					op:    TokenTypeMINUS,
+					left:  &binaryNode{op: TokenTypeMULTI, left: left, right: n.right, s: n.s},
+					right: &binaryNode{op: TokenTypeMULTI, left: n.left, right: right, s: n.s},
+					s:     n.s,
+				},
+				right: &binaryNode{op: TokenTypeMULTI, left: n.right, right: n.right, s: n.s},
+				s:     n.s,
+			}, nil
+		}
+	}
+	panic(fmt.Sprintf("unknown node %T", n))
+}
+
+func (d *differentiator) variableDerivative(n *variableNode) (node, error) {
+	path, ok := n.staticPath()
+	if !ok {
+		// A variable with index expression depending on the variable cannot be differentiated.
+		for _, element := range n.path {
+			if element.index == nil {
+				continue
+			}
+			derivative, err := d.derivative(element.index)
+			if err != nil {
+				return nil, err
+			}
+			if value, ok := constantValue(d.simplifier.simplify(derivative)); !ok || value != 0 {
+				return nil, errors.New(fmt.Sprintf("index of variable at position %s depends on '%s'", n.s.Start, d.variable))
+			}
+		}
+		return newConstantNode(0, n.s), nil
+	}
+
+	if (len(path) == 1 && path[0].Name == d.variable) || path.String() == d.variable {
+		return newConstantNode(1, n.s), nil
+	}
+	return newConstantNode(0, n.s), nil
+}
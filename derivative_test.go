@@ -0,0 +1,47 @@
+package goculator
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDerivative(t *testing.T) {
+	assert := assert.New(t)
+	var testdata = []struct {
+		input    string
+		variable string
+		result   string
+	}{
+		{"3", "x", "0"},
+		{"x", "x", "1"},
+		{"y", "x", "0"},
+		{"-x", "x", "-1"},
+		{"2 * x + 3", "x", "2"},
+		{"x * y - y", "x", "y"},
+		{"x * x", "x", "x + x"},
+		{"1 / x", "x", "-1 / (x * x)"},
+		{"x / y", "x", "1 / y"},
+		{"(price - cost) * qty / price", "qty", "(price - cost) / price"},
+		{"(x + 1) / (x - 1)", "x", "(x - 1 - (x + 1)) / ((x - 1) * (x - 1))"},
+		{"order.price * order.qty", "order.price", "order.qty"},
+		{"`unit price` * qty", "unit price", "qty"},
+		{"items[i].price * x", "x", "items[i].price"},
+		{"", "x", "0"},
+	}
+
+	for _, data := range testdata {
+		expr, err := Parse(data.input)
+		if !assert.NoError(err, data.input) {
+			continue
+		}
+
+		derivative, err := Derivative(expr, data.variable)
+		if assert.NoError(err, data.input) {
+			assert.Equal(data.result, derivative.String(), data.input)
+		}
+	}
+
+	expr, _ := Parse("items[x].price")
+	_, err := Derivative(expr, "x")
+	assert.EqualError(err, "index of variable at position 0 depends on 'x'")
+}
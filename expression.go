@@ -0,0 +1,201 @@
+package goculator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+)
+
+// Expression is parsed arithmetic expression. Expression is immutable and can be evaluated many times.
+type Expression struct {
+	input   string
+	root    node
+	options options
+}
+
+// Parse parses input and returns Expression.
+func Parse(input string, opts ...Option) (*Expression, error) {
+	options := newOptions(opts)
+	p := &parser{lexer: NewLexer(input, opts...), limits: options.limits}
+	root, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	return &Expression{input: input, root: root, options: options}, nil
+}
+
+// Eval evaluates Expression with variables in context and returns result and error.
+// context can be nil if Expression has no variable.
+func (e *Expression) Eval(context Context) (float64, error) {
+	return e.evaluate(nil, context)
+}
+
+// evaluatorPool keeps evaluators for reuse. Every evaluation gets its own evaluator,
+// so Expression can be evaluated by goroutines at the same time.
+var evaluatorPool = sync.Pool{New: func() interface{} { return new(evaluator) }}
+
+// evaluate evaluates Expression with an evaluator from evaluatorPool and checks ctx for cancellation if ctx is not nil.
+func (e *Expression) evaluate(ctx context.Context, vars Context) (float64, error) {
+	if e.root == nil {
+		return 0, nil
+	}
+	ev := evaluatorPool.Get().(*evaluator)
+	*ev = evaluator{context: vars, maxSteps: e.options.limits.MaxSteps, policy: e.options.policy, ctx: ctx}
+	result, err := ev.eval(e.root)
+	// Clearing evaluator releases vars and ctx before evaluator is reused.
+	*ev = evaluator{}
+	evaluatorPool.Put(ev)
+	return result, err
+}
+
+// evaluator evaluates nodes by walking the tree.
+type evaluator struct {
+	context  Context
+	steps    int
+	maxSteps int
+	policy   Policy
+	// ctx is checked for cancellation if it is not nil.
+	ctx context.Context
+	// tracer records evaluation steps if it is not nil.
+	tracer *tracer
+}
+
+func (ev *evaluator) eval(n node) (float64, error) {
+	if ev.tracer == nil {
+		return ev.evalNode(n)
+	}
+	step := ev.tracer.begin(n)
+	result, err := ev.evalNode(n)
+	ev.tracer.end(step, result, err)
+	return result, err
+}
+
+func (ev *evaluator) evalNode(n node) (float64, error) {
+	ev.steps++
+	if ev.maxSteps > 0 && ev.steps > ev.maxSteps {
+		return 0, &StepLimitError{Max: ev.maxSteps}
+	}
+	if ev.ctx != nil && ev.steps%cancelCheckInterval == 0 {
+		if err := ev.ctx.Err(); err != nil {
+			return 0, err
+		}
+	}
+
+	switch n := n.(type) {
+	case *numberNode:
+		return n.value, nil
+	case *variableNode:
+		var result float64
+		var err error
+		if len(n.path) == 1 {
+			result, err = valueContext(ev.ctx, ev.context, n.path[0].name)
+		} else {
+			var path Path
+			path, err = ev.evalPath(n)
+			if err != nil {
+				return 0, err
+			}
+			result, err = pathValueContext(ev.ctx, ev.context, path)
+		}
+		if err != nil {
+			return 0, err
+		}
+		if !ev.policy.allowsVariable(result) {
+			return 0, variableError(variableKey(n), result, n.s.Start)
+		}
+		return result, nil
+	case *binaryNode:
+		left, err := ev.eval(n.left)
+		if err != nil {
+			return 0, err
+		}
+		right, err := ev.eval(n.right)
+		if err != nil {
+			return 0, err
+		}
+		result := binary(n.op, left, right)
+		if err := ev.policy.checkBinary(n.op, left, right, result, n.opPos); err != nil {
+			return 0, err
+		}
+		return result, nil
+	case *unaryNode:
+		operand, err := ev.eval(n.operand)
+		if err != nil {
+			return 0, err
+		}
+		return unary(n.op, operand), nil
+	}
+	panic(fmt.Sprintf("unknown node %T", n))
+}
+
+// evalPath evaluates index expressions of variable and returns Path.
+func (ev *evaluator) evalPath(n *variableNode) (Path, error) {
+	path := make(Path, len(n.path))
+	for i, element := range n.path {
+		if element.index == nil {
+			path[i] = PathElement{Name: element.name}
+			continue
+		}
+
+		result, err := ev.eval(element.index)
+		if err != nil {
+			return nil, err
+		}
+		index, ok := toIndex(result)
+		if !ok {
+			return nil, errors.New(fmt.Sprintf("index %v of '%s' is not a non-negative integer", result, path[:i]))
+		}
+		path[i] = PathElement{Index: index, IsIndex: true}
+	}
+	return path, nil
+}
+
+// toIndex converts value to index of Path. It reports false if value is not a non-negative integer.
+func toIndex(value float64) (int, bool) {
+	if value != math.Trunc(value) || value < 0 || value > math.MaxInt32 {
+		return 0, false
+	}
+	return int(value), true
+}
+
+func binary(op TokenType, left, right float64) float64 {
+	switch op {
+	case TokenTypePLUS:
+		return left + right
+	case TokenTypeMINUS:
+		return left - right
+	case TokenTypeMULTI:
+		return left * right
+	case TokenTypeDIV:
+		return left / right
+	}
+	panic(fmt.Sprintf("unknown operator %s", op))
+}
+
+func unary(op TokenType, operand float64) float64 {
+	if op == TokenTypeMINUS {
+		return -operand
+	}
+	return operand
+}
+
+func value(context Context, key string) (float64, error) {
+	if context == nil {
+		return 0, errors.New("no context given for variable")
+	}
+
+	return context.Value(key)
+}
+
+func pathValue(context Context, path Path) (float64, error) {
+	if context == nil {
+		return 0, errors.New("no context given for variable")
+	}
+
+	if context, ok := context.(PathContext); ok {
+		return context.PathValue(path)
+	}
+	return context.Value(path.String())
+}
@@ -0,0 +1,198 @@
+package goculator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// FormulaSet is a set of named formulas which refer to each other by name,
+// e.g. gross = price * qty and net = gross - discount.
+type FormulaSet struct {
+	formulas map[string]*Expression
+	names    []string
+	opts     []Option
+}
+
+// CycleError is returned if formulas of FormulaSet refer to each other in a cycle.
+type CycleError struct {
+	// Path is the names of formulas in the cycle. The first name is repeated at the end, e.g. [a b c a].
+	Path []string
+}
+
+func (e *CycleError) Error() string {
+	return fmt.Sprintf("formulas have a cycle: %s", strings.Join(e.Path, " -> "))
+}
+
+// NewFormulaSet returns new empty FormulaSet. opts are used to parse formulas.
+func NewFormulaSet(opts ...Option) *FormulaSet {
+	s := new(FormulaSet)
+	s.formulas = make(map[string]*Expression)
+	s.opts = opts
+	return s
+}
+
+// Add parses formula and adds it with name. Other formulas can refer to it by name as a variable.
+func (s *FormulaSet) Add(name string, formula string) error {
+	expr, err := Parse(formula, s.opts...)
+	if err != nil {
+		return errors.New(fmt.Sprintf("formula '%s': %s", name, err))
+	}
+	return s.AddExpression(name, expr)
+}
+
+// AddExpression adds expr with name.
+func (s *FormulaSet) AddExpression(name string, expr *Expression) error {
+	if _, ok := s.formulas[name]; ok {
+		return errors.New(fmt.Sprintf("formula '%s' already exists", name))
+	}
+	s.formulas[name] = expr
+	s.names = append(s.names, name)
+	return nil
+}
+
+// Names returns names of formulas in the order they are added.
+func (s *FormulaSet) Names() []string {
+	return append([]string(nil), s.names...)
+}
+
+// Expression returns formula of name.
+func (s *FormulaSet) Expression(name string) (*Expression, bool) {
+	expr, ok := s.formulas[name]
+	return expr, ok
+}
+
+// Dependencies returns names of formulas which formula of name refers to.
+func (s *FormulaSet) Dependencies(name string) []string {
+	expr, ok := s.formulas[name]
+	if !ok {
+		return nil
+	}
+
+	dependencies := make([]string, 0)
+	for _, variable := range expr.VariableNames() {
+		if _, ok := s.formulas[variable]; ok {
+			dependencies = append(dependencies, variable)
+		}
+	}
+	return dependencies
+}
+
+// Order returns names of formulas in topological order, where every formula comes after the formulas it refers to.
+// It returns *CycleError if formulas refer to each other in a cycle.
+func (s *FormulaSet) Order() ([]string, error) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	states := make(map[string]int)
+	order := make([]string, 0, len(s.names))
+	var stack []string
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch states[name] {
+		case visited:
+			return nil
+		case visiting:
+			for i, n := range stack {
+				if n == name {
+					return &CycleError{Path: append(append([]string(nil), stack[i:]...), name)}
+				}
+			}
+		}
+
+		states[name] = visiting
+		stack = append(stack, name)
+		for _, dependency := range s.Dependencies(name) {
+			if err := visit(dependency); err != nil {
+				return err
+			}
+		}
+		stack = stack[:len(stack)-1]
+		states[name] = visited
+		order = append(order, name)
+		return nil
+	}
+
+	for _, name := range s.names {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// Evaluate evaluates all formulas in topological order and returns results by name.
+// Variables which are not formulas are looked up in context.
+func (s *FormulaSet) Evaluate(context Context) (map[string]float64, error) {
+	return s.evaluate(nil, context)
+}
+
+// EvaluateContext is Evaluate which stops with the error of ctx when ctx is canceled or its deadline is exceeded.
+func (s *FormulaSet) EvaluateContext(ctx context.Context, vars Context) (map[string]float64, error) {
+	return s.evaluate(ctx, vars)
+}
+
+// evaluate evaluates all formulas and checks ctx for cancellation if ctx is not nil.
+func (s *FormulaSet) evaluate(ctx context.Context, vars Context) (map[string]float64, error) {
+	order, err := s.Order()
+	if err != nil {
+		return nil, err
+	}
+
+	values := &formulaContext{values: make(map[string]float64, len(order)), parent: vars}
+	for _, name := range order {
+		var result float64
+		var err error
+		if ctx != nil {
+			result, err = s.formulas[name].EvalContext(ctx, values)
+		} else {
+			result, err = s.formulas[name].Eval(values)
+		}
+		if err != nil {
+			if ctx != nil && ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, errors.New(fmt.Sprintf("formula '%s': %s", name, err))
+		}
+		values.values[name] = result
+	}
+	return values.values, nil
+}
+
+// formulaContext is CancelableContext and CancelablePathContext which looks up results of formulas first and then parent.
+type formulaContext struct {
+	values map[string]float64
+	parent Context
+}
+
+func (c *formulaContext) Value(key string) (float64, error) {
+	if value, ok := c.values[key]; ok {
+		return value, nil
+	}
+	return value(c.parent, key)
+}
+
+func (c *formulaContext) ValueContext(ctx context.Context, key string) (float64, error) {
+	if value, ok := c.values[key]; ok {
+		return value, nil
+	}
+	return valueContext(ctx, c.parent, key)
+}
+
+func (c *formulaContext) PathValue(path Path) (float64, error) {
+	if value, ok := c.values[path.String()]; ok {
+		return value, nil
+	}
+	return pathValue(c.parent, path)
+}
+
+func (c *formulaContext) PathValueContext(ctx context.Context, path Path) (float64, error) {
+	if value, ok := c.values[path.String()]; ok {
+		return value, nil
+	}
+	return pathValueContext(ctx, c.parent, path)
+}
@@ -0,0 +1,53 @@
+package goculator
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestFormulaSet(t *testing.T) {
+	assert := assert.New(t)
+
+	set := NewFormulaSet()
+	assert.NoError(set.Add("net", "gross - discount"))
+	assert.NoError(set.Add("gross", "price * qty"))
+	assert.NoError(set.Add("margin", "net / gross"))
+	assert.NoError(set.Add("discount", "gross * rate"))
+	assert.EqualError(set.Add("gross", "1"), "formula 'gross' already exists")
+	assert.EqualError(set.Add("bad", "1 +"), "formula 'bad': expected token type NUM is not matching currunt token type EOF at position 3")
+
+	order, err := set.Order()
+	assert.NoError(err)
+	assert.Equal([]string{"gross", "discount", "net", "margin"}, order)
+	assert.Equal([]string{"gross", "discount"}, set.Dependencies("net"))
+
+	values, err := set.Evaluate(NewDefaultContext(map[string]float64{"price": 10, "qty": 3, "rate": 0.1}))
+	assert.NoError(err)
+	assert.Equal(map[string]float64{"gross": 30, "discount": 3, "net": 27, "margin": 0.9}, values)
+
+	_, err = set.Evaluate(NewDefaultContext(map[string]float64{"price": 10, "qty": 3}))
+	assert.EqualError(err, "formula 'discount': no value for key 'rate'")
+}
+
+func TestFormulaSetCycle(t *testing.T) {
+	assert := assert.New(t)
+
+	set := NewFormulaSet()
+	assert.NoError(set.Add("a", "b + 1"))
+	assert.NoError(set.Add("b", "c * 2"))
+	assert.NoError(set.Add("c", "x + a"))
+
+	_, err := set.Order()
+	assert.EqualError(err, "formulas have a cycle: a -> b -> c -> a")
+	if cycle, ok := err.(*CycleError); assert.True(ok) {
+		assert.Equal([]string{"a", "b", "c", "a"}, cycle.Path)
+	}
+
+	_, err = set.Evaluate(nil)
+	assert.Error(err)
+
+	set = NewFormulaSet()
+	assert.NoError(set.Add("a", "a + 1"))
+	_, err = set.Order()
+	assert.EqualError(err, "formulas have a cycle: a -> a")
+}
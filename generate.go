@@ -0,0 +1,346 @@
+package goculator
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// GenerateOptions configures GenerateGo and GenerateGoTest.
+type GenerateOptions struct {
+	// Package is the package name of generated code.
+	Package string
+	// Source is the name of the formula file, which is written in the header of generated code.
+	Source string
+	// TestName is the name of the test function generated by GenerateGoTest. Default is "TestFormulas".
+	TestName string
+}
+
+// ReadFormulas reads formulas from r into new FormulaSet. Each line is a formula of the form "name = formula".
+// Empty lines and lines starting with "#" or "//" are skipped. opts are used to parse formulas.
+func ReadFormulas(r io.Reader, opts ...Option) (*FormulaSet, error) {
+	set := NewFormulaSet(opts...)
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") || strings.HasPrefix(text, "//") {
+			continue
+		}
+
+		i := strings.Index(text, "=")
+		if i < 0 {
+			return nil, errors.New(fmt.Sprintf("line %d: expected 'name = formula'", line))
+		}
+		if err := set.Add(strings.TrimSpace(text[:i]), text[i+1:]); err != nil {
+			return nil, errors.New(fmt.Sprintf("line %d: %s", line, err))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// GenerateGo returns Go source code with a function for each formula of set, e.g.
+// func Margin(price, cost float64) float64 for margin = price - cost.
+// The name of a function is the name of its formula starting with an upper case letter,
+// and its parameters are the variables which are not formulas in the order of appearance.
+// A formula which refers to other formulas calls their functions.
+//
+// Functions compute exactly like Eval with PolicyIEEE. Constant sub-expressions are folded with float64 arithmetic,
+// and products are converted to float64 explicitly so that the compiler does not fuse them with addition.
+func GenerateGo(set *FormulaSet, options GenerateOptions) ([]byte, error) {
+	g, err := newGoGenerator(set)
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	for _, name := range set.Names() {
+		expr, _ := set.Expression(name)
+		code, formula := "0", "0"
+		if expr.root != nil {
+			code, _ = g.emit(expr.root, true)
+			formula = defaultString(expr)
+		}
+		fmt.Fprintf(&body, "\n// %s returns %s.\n", g.funcs[name], formula)
+		fmt.Fprintf(&body, "func %s(%s) float64 {\n\treturn %s\n}\n", g.funcs[name], g.signature(name), code)
+	}
+
+	var b bytes.Buffer
+	g.header(&b, options)
+	if g.usesMath {
+		b.WriteString("import \"math\"\n")
+	}
+	b.Write(body.Bytes())
+	return formatSource(b.Bytes())
+}
+
+// GenerateGoTest returns Go source code of a test which checks that the functions generated by GenerateGo
+// return exactly the same results as FormulaSet.Evaluate for random values.
+func GenerateGoTest(set *FormulaSet, options GenerateOptions) ([]byte, error) {
+	g, err := newGoGenerator(set)
+	if err != nil {
+		return nil, err
+	}
+	testName := options.TestName
+	if testName == "" {
+		testName = "TestFormulas"
+	}
+
+	var b bytes.Buffer
+	g.header(&b, options)
+	b.WriteString(`import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/yhmin84/goculator"
+)
+`)
+	fmt.Fprintf(&b, "\nfunc %s(t *testing.T) {\n", testName)
+	b.WriteString("set := goculator.NewFormulaSet()\n")
+	b.WriteString("formulas := []struct{ name, formula string }{\n")
+	for _, name := range set.Names() {
+		expr, _ := set.Expression(name)
+		fmt.Fprintf(&b, "{%s, %s},\n", strconv.Quote(name), strconv.Quote(defaultString(expr)))
+	}
+	b.WriteString(`}
+	for _, f := range formulas {
+		if err := set.Add(f.name, f.formula); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	check := func(name string, values map[string]float64, expected, actual float64) {
+		if math.Float64bits(expected) != math.Float64bits(actual) && !(math.IsNaN(expected) && math.IsNaN(actual)) {
+			t.Errorf("%s with %v: expected %v, got %v", name, values, expected, actual)
+		}
+	}
+
+	r := rand.New(rand.NewSource(1))
+	samples := []float64{0, math.Copysign(0, -1), 1, -1, 0.1, 3, math.MaxFloat64, math.SmallestNonzeroFloat64}
+	value := func() float64 {
+		if r.Intn(4) == 0 {
+			return samples[r.Intn(len(samples))]
+		}
+		return r.NormFloat64() * 1000
+	}
+
+	for i := 0; i < 1000; i++ {
+		values := map[string]float64{
+`)
+	for _, input := range g.inputs {
+		fmt.Fprintf(&b, "%s: value(),\n", strconv.Quote(input))
+	}
+	b.WriteString(`}
+		results, err := set.Evaluate(goculator.NewDefaultContext(values))
+		if err != nil {
+			t.Fatal(err)
+		}
+`)
+	for _, name := range set.Names() {
+		args := make([]string, len(g.params[name]))
+		for i, param := range g.params[name] {
+			args[i] = fmt.Sprintf("values[%s]", strconv.Quote(param))
+		}
+		fmt.Fprintf(&b, "check(%s, values, results[%s], %s(%s))\n",
+			strconv.Quote(name), strconv.Quote(name), g.funcs[name], strings.Join(args, ", "))
+	}
+	b.WriteString("}\n}\n")
+	return formatSource(b.Bytes())
+}
+
+// defaultString returns Expression printed with DefaultLocale, which the generated test parses with.
+func defaultString(expr *Expression) string {
+	p := printer{}
+	if expr.root != nil {
+		p.print(expr.root, false)
+	}
+	return p.buf.String()
+}
+
+func formatSource(source []byte) ([]byte, error) {
+	formatted, err := format.Source(source)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("generated code is invalid: %s", err))
+	}
+	return formatted, nil
+}
+
+// goGenerator generates Go code of formulas.
+type goGenerator struct {
+	set *FormulaSet
+	// funcs are the names of functions by formula.
+	funcs map[string]string
+	// params are the parameters of functions by formula.
+	params map[string][]string
+	// inputs are the variables which are not formulas in the order of appearance in all formulas.
+	inputs   []string
+	usesMath bool
+}
+
+func newGoGenerator(set *FormulaSet) (*goGenerator, error) {
+	order, err := set.Order()
+	if err != nil {
+		return nil, err
+	}
+
+	g := &goGenerator{set: set, funcs: make(map[string]string), params: make(map[string][]string)}
+	formulas := make(map[string]string)
+	for _, name := range set.Names() {
+		funcName, ok := exportedName(name)
+		if !ok {
+			return nil, errors.New(fmt.Sprintf("formula name '%s' cannot be a Go function name", name))
+		}
+		if other, ok := formulas[funcName]; ok {
+			return nil, errors.New(fmt.Sprintf("formulas '%s' and '%s' have the same function name %s", other, name, funcName))
+		}
+		formulas[funcName] = name
+		g.funcs[name] = funcName
+	}
+
+	seenInputs := make(map[string]bool)
+	for _, name := range order {
+		expr, _ := set.Expression(name)
+		seen := make(map[string]bool)
+		for _, reference := range expr.Variables() {
+			if _, ok := set.Expression(reference.Name); ok {
+				for _, param := range g.params[reference.Name] {
+					if !seen[param] {
+						seen[param] = true
+						g.params[name] = append(g.params[name], param)
+					}
+				}
+				continue
+			}
+
+			switch {
+			case reference.Name != reference.Root || !token.IsIdentifier(reference.Name):
+				return nil, errors.New(fmt.Sprintf("formula '%s': variable '%s' cannot be a Go parameter name", name, reference.Name))
+			case reference.Name == "float64" || reference.Name == "math" || formulas[reference.Name] != "":
+				return nil, errors.New(fmt.Sprintf("formula '%s': variable '%s' conflicts with generated code", name, reference.Name))
+			}
+			if !seen[reference.Name] {
+				seen[reference.Name] = true
+				g.params[name] = append(g.params[name], reference.Name)
+			}
+			if !seenInputs[reference.Name] {
+				seenInputs[reference.Name] = true
+				g.inputs = append(g.inputs, reference.Name)
+			}
+		}
+	}
+	return g, nil
+}
+
+// exportedName returns name starting with an upper case letter, and reports whether it is an exported Go identifier.
+func exportedName(name string) (string, bool) {
+	first, size := utf8.DecodeRuneInString(name)
+	exported := string(unicode.ToUpper(first)) + name[size:]
+	return exported, token.IsIdentifier(exported) && token.IsExported(exported)
+}
+
+func (g *goGenerator) header(b *bytes.Buffer, options GenerateOptions) {
+	if options.Source != "" {
+		fmt.Fprintf(b, "// Code generated by goculator gen from %s. DO NOT EDIT.\n\n", options.Source)
+	} else {
+		b.WriteString("// Code generated by goculator gen. DO NOT EDIT.\n\n")
+	}
+	fmt.Fprintf(b, "package %s\n\n", options.Package)
+}
+
+func (g *goGenerator) signature(name string) string {
+	if len(g.params[name]) == 0 {
+		return ""
+	}
+	return strings.Join(g.params[name], ", ") + " float64"
+}
+
+// emit returns Go code of n and its precedence. fusable is true if n is not an operand of multiplication or division,
+// where a product could be fused with an enclosing addition.
+func (g *goGenerator) emit(n node, fusable bool) (string, int) {
+	if _, ok := n.(*numberNode); !ok && isConstantNode(n) {
+		// Go evaluates constant expressions exactly, so they are folded with float64 arithmetic like Eval.
+		ev := evaluator{}
+		value, _ := ev.eval(n)
+		return g.literal(value)
+	}
+
+	switch n := n.(type) {
+	case *numberNode:
+		return g.literal(n.value)
+	case *variableNode:
+		name := n.path[0].name
+		if _, ok := g.set.Expression(name); ok {
+			return fmt.Sprintf("%s(%s)", g.funcs[name], strings.Join(g.params[name], ", ")), precedencePrimary
+		}
+		return name, precedencePrimary
+	case *unaryNode:
+		if n.op != TokenTypeMINUS {
+			return g.emit(n.operand, fusable)
+		}
+		code, prec := g.emit(n.operand, true)
+		// Parentheses also keep "- -x" from being "--x".
+		if prec < precedenceUnary || strings.HasPrefix(code, "-") {
+			code = "(" + code + ")"
+		}
+		return "-" + code, precedenceUnary
+	case *binaryNode:
+		prec := precedence(n)
+		operandFusable := prec == precedenceAdditive
+		left, leftPrec := g.emit(n.left, operandFusable)
+		right, rightPrec := g.emit(n.right, operandFusable)
+		if leftPrec < prec {
+			left = "(" + left + ")"
+		}
+		if rightPrec <= prec {
+			right = "(" + right + ")"
+		}
+		code := left + " " + operatorStrings[n.op] + " " + right
+		if n.op == TokenTypeMULTI && fusable {
+			return "float64(" + code + ")", precedencePrimary
+		}
+		return code, prec
+	}
+	panic(fmt.Sprintf("unknown node %T", n))
+}
+
+// literal returns Go code of value and its precedence.
+func (g *goGenerator) literal(value float64) (string, int) {
+	switch {
+	case math.IsNaN(value):
+		g.usesMath = true
+		return "math.NaN()", precedencePrimary
+	case math.IsInf(value, 0):
+		g.usesMath = true
+		return fmt.Sprintf("math.Inf(%d)", int(math.Copysign(1, value))), precedencePrimary
+	case isNegativeZero(value):
+		// Go constants have no negative zero.
+		g.usesMath = true
+		return "math.Copysign(0, -1)", precedencePrimary
+	case value < 0:
+		return "-" + formatFloat(-value), precedenceUnary
+	}
+	return formatFloat(value), precedencePrimary
+}
+
+// isConstantNode reports whether n has no variable.
+func isConstantNode(n node) bool {
+	constant := true
+	walk(n, func(n node) {
+		if _, ok := n.(*variableNode); ok {
+			constant = false
+		}
+	})
+	return constant
+}
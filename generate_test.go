@@ -0,0 +1,174 @@
+package goculator
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerateGo(t *testing.T) {
+	assert := assert.New(t)
+
+	set, err := ReadFormulas(strings.NewReader(`
+# pricing
+margin = price - cost
+net = margin * qty - fee * 2 + 0.1 * 3
+`))
+	if !assert.NoError(err) {
+		return
+	}
+
+	source, err := GenerateGo(set, GenerateOptions{Package: "pricing", Source: "pricing.formulas"})
+	assert.NoError(err)
+	assert.Equal(`// Code generated by goculator gen from pricing.formulas. DO NOT EDIT.
+
+package pricing
+
+// Margin returns price - cost.
+func Margin(price, cost float64) float64 {
+	return price - cost
+}
+
+// Net returns margin * qty - fee * 2 + 0.1 * 3.
+func Net(price, cost, qty, fee float64) float64 {
+	return float64(Margin(price, cost)*qty) - float64(fee*2) + 0.30000000000000004
+}
+`, string(source))
+
+	source, err = GenerateGoTest(set, GenerateOptions{Package: "pricing"})
+	assert.NoError(err)
+	assert.Contains(string(source), "func TestFormulas(t *testing.T) {")
+	assert.Contains(string(source), `check("net", values, results["net"], Net(values["price"], values["cost"], values["qty"], values["fee"]))`)
+}
+
+// TestGenerateGoRun writes the generated package and its test to a temporary GOPATH with a copy of this package,
+// and runs go test on it, so that the generated code is checked by the compiler and against the interpreter.
+func TestGenerateGoRun(t *testing.T) {
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go tool is not available")
+	}
+	if testing.Short() {
+		t.Skip("skipping go test of generated code in short mode")
+	}
+	assert := assert.New(t)
+
+	set, err := ReadFormulas(strings.NewReader(`
+margin = price - cost
+net = margin * qty - fee * 2 + 0.1 * 3
+ratio = -(margin / 가격) * -0
+limit = x / (1 / 0) + x / -(0 / 0)
+`))
+	if !assert.NoError(err) {
+		return
+	}
+	options := GenerateOptions{Package: "pricing", Source: "pricing.formulas"}
+	source, err := GenerateGo(set, options)
+	if !assert.NoError(err) {
+		return
+	}
+	test, err := GenerateGoTest(set, options)
+	if !assert.NoError(err) {
+		return
+	}
+
+	gopath, err := ioutil.TempDir("", "goculator")
+	if !assert.NoError(err) {
+		return
+	}
+	defer os.RemoveAll(gopath)
+
+	// The generated test imports this package, whose sources are copied without tests.
+	files, err := filepath.Glob("*.go")
+	if !assert.NoError(err) {
+		return
+	}
+	library := filepath.Join(gopath, "src", "github.com", "yhmin84", "goculator")
+	assert.NoError(os.MkdirAll(library, 0755))
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		content, err := ioutil.ReadFile(file)
+		if assert.NoError(err) {
+			assert.NoError(ioutil.WriteFile(filepath.Join(library, file), content, 0644))
+		}
+	}
+
+	pkg := filepath.Join(gopath, "src", "pricing")
+	assert.NoError(os.MkdirAll(pkg, 0755))
+	assert.NoError(ioutil.WriteFile(filepath.Join(pkg, "pricing.go"), source, 0644))
+	assert.NoError(ioutil.WriteFile(filepath.Join(pkg, "pricing_test.go"), test, 0644))
+	if t.Failed() {
+		return
+	}
+
+	cmd := exec.Command(goTool, "test")
+	cmd.Dir = pkg
+	cmd.Env = append(os.Environ(), "GOPATH="+gopath, "GO111MODULE=off", "GOFLAGS=")
+	output, err := cmd.CombinedOutput()
+	assert.NoError(err, string(output))
+}
+
+func TestGenerateGoLiteral(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		formula string
+		code    string
+	}{
+		{"-(-x)", "-(-x)"},
+		{"x - -2", "x - -2"},
+		{"-(x * y) + 1", "-float64(x*y) + 1"},
+		{"x * y / z", "x * y / z"},
+		{"x / (y * z)", "x / (y * z)"},
+		{"x - (y - 1)", "x - (y - 1)"},
+		{"x / (1 / 0)", "x / math.Inf(1)"},
+		{"x / -(0 / 0)", "x / math.NaN()"},
+		{"x * -0", "float64(x * math.Copysign(0, -1))"},
+	}
+
+	for _, test := range tests {
+		set := NewFormulaSet()
+		set.Add("f", test.formula)
+		source, err := GenerateGo(set, GenerateOptions{Package: "p"})
+		if assert.NoError(err, test.formula) {
+			assert.Contains(string(source), "\treturn "+test.code+"\n", test.formula)
+		}
+	}
+}
+
+func TestGenerateGoError(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		formulas string
+		err      string
+	}{
+		{"a = items[0].price", "formula 'a': variable 'items[0].price' cannot be a Go parameter name"},
+		{"a = 가격 * func", "formula 'a': variable 'func' cannot be a Go parameter name"},
+		{"a = float64 * 2", "formula 'a': variable 'float64' conflicts with generated code"},
+		{"a = B * 2\nb = 1", "formula 'a': variable 'B' conflicts with generated code"},
+		{"a = b\nb = a", "formulas have a cycle: a -> b -> a"},
+		{"_a = 1", "formula name '_a' cannot be a Go function name"},
+		{"a = 1\nA = 2", "formulas 'a' and 'A' have the same function name A"},
+	}
+
+	for _, test := range tests {
+		set, err := ReadFormulas(strings.NewReader(test.formulas))
+		if !assert.NoError(err, test.formulas) {
+			continue
+		}
+		_, err = GenerateGo(set, GenerateOptions{Package: "p"})
+		assert.EqualError(err, test.err, test.formulas)
+	}
+
+	_, err := ReadFormulas(strings.NewReader("a = 1\nb"))
+	assert.EqualError(err, "line 2: expected 'name = formula'")
+	_, err = ReadFormulas(strings.NewReader("a = 1 +"))
+	assert.Error(err)
+}
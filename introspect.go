@@ -0,0 +1,120 @@
+package goculator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Reference is a variable referenced in Expression.
+type Reference struct {
+	// Name is the key given to Context.Value for a plain variable or a path without index expressions,
+	// e.g. "x" or "items[2].price". For a path with index expressions, Name is the path as printed
+	// by Expression.String, e.g. "items[i].price".
+	Name string
+	// Root is the first name of the variable path, e.g. "items" for "items[i].price".
+	Root string
+	Span Span
+}
+
+// Constant is a number literal in Expression.
+type Constant struct {
+	Literal string
+	Value   float64
+	Span    Span
+}
+
+// Variables returns all variables referenced in Expression in the order of appearance.
+// Variables in index expressions are also returned.
+func (e *Expression) Variables() []Reference {
+	references := make([]Reference, 0)
+	walk(e.root, func(n node) {
+		if variable, ok := n.(*variableNode); ok {
+			references = append(references, Reference{
+				Name: variableKey(variable),
+				Root: variable.path[0].name,
+				Span: variable.s,
+			})
+		}
+	})
+	return references
+}
+
+// VariableNames returns the names of variables referenced in Expression without duplicates.
+func (e *Expression) VariableNames() []string {
+	names := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, reference := range e.Variables() {
+		if !seen[reference.Name] {
+			seen[reference.Name] = true
+			names = append(names, reference.Name)
+		}
+	}
+	return names
+}
+
+// Constants returns all number literals in Expression in the order of appearance.
+func (e *Expression) Constants() []Constant {
+	constants := make([]Constant, 0)
+	walk(e.root, func(n node) {
+		if number, ok := n.(*numberNode); ok {
+			constants = append(constants, Constant{Literal: number.literal, Value: number.value, Span: number.s})
+		}
+	})
+	return constants
+}
+
+// Validate returns error if Expression references a variable which is not in allowed.
+// A variable is allowed if its Name or its Root is in allowed.
+func (e *Expression) Validate(allowed []string) error {
+	allowedSet := make(map[string]bool)
+	for _, name := range allowed {
+		allowedSet[name] = true
+	}
+
+	var unknowns []string
+	for _, reference := range e.Variables() {
+		if !allowedSet[reference.Name] && !allowedSet[reference.Root] {
+			unknowns = append(unknowns, fmt.Sprintf("'%s' at position %s", reference.Name, reference.Span.Start))
+		}
+	}
+
+	if len(unknowns) > 0 {
+		return errors.New(fmt.Sprintf("unknown variable %s", strings.Join(unknowns, ", ")))
+	}
+	return nil
+}
+
+// walk calls f for n and all its descendants in the order of appearance.
+func walk(n node, f func(node)) {
+	switch n := n.(type) {
+	case nil:
+		return
+	case *variableNode:
+		f(n)
+		for _, element := range n.path {
+			walk(element.index, f)
+		}
+	case *binaryNode:
+		walk(n.left, f)
+		f(n)
+		walk(n.right, f)
+	case *unaryNode:
+		f(n)
+		walk(n.operand, f)
+	default:
+		f(n)
+	}
+}
+
+// variableKey returns the key of variable given to Context.Value, or the variable as printed by Expression.String
+// if it has index expressions.
+func variableKey(n *variableNode) string {
+	if path, ok := n.staticPath(); ok {
+		if len(path) == 1 {
+			return path[0].Name
+		}
+		return path.String()
+	}
+	return variableString(n)
+}
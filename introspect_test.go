@@ -0,0 +1,47 @@
+package goculator
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestVariables(t *testing.T) {
+	assert := assert.New(t)
+
+	expr, err := Parse("price * `unit qty` - items[i + 1].price / price + 0x10")
+	assert.NoError(err)
+
+	assert.Equal(
+		[]Reference{
+			Reference{Name: "price", Root: "price", Span: Span{Position{0, 0}, Position{5, 5}}},
+			Reference{Name: "unit qty", Root: "unit qty", Span: Span{Position{8, 8}, Position{18, 18}}},
+			Reference{Name: "items[i + 1].price", Root: "items", Span: Span{Position{21, 21}, Position{39, 39}}},
+			Reference{Name: "i", Root: "i", Span: Span{Position{27, 27}, Position{28, 28}}},
+			Reference{Name: "price", Root: "price", Span: Span{Position{42, 42}, Position{47, 47}}},
+		},
+		expr.Variables(),
+	)
+	assert.Equal([]string{"price", "unit qty", "items[i + 1].price", "i"}, expr.VariableNames())
+	assert.Equal(
+		[]Constant{
+			Constant{Literal: "1", Value: 1, Span: Span{Position{31, 31}, Position{32, 32}}},
+			Constant{Literal: "0x10", Value: 16, Span: Span{Position{50, 50}, Position{54, 54}}},
+		},
+		expr.Constants(),
+	)
+
+	expr, _ = Parse("")
+	assert.Empty(expr.Variables())
+}
+
+func TestValidate(t *testing.T) {
+	assert := assert.New(t)
+
+	expr, _ := Parse("price * qty + order.customer.tier - größe")
+	assert.NoError(expr.Validate([]string{"price", "qty", "order", "größe"}))
+	assert.NoError(expr.Validate([]string{"price", "qty", "order.customer.tier", "größe"}))
+	assert.EqualError(
+		expr.Validate([]string{"price", "order.customer"}),
+		"unknown variable 'qty' at position 8, 'order.customer.tier' at position 14, 'größe' at position 36",
+	)
+}
@@ -3,17 +3,29 @@ package goculator
 import (
 	"errors"
 	"fmt"
-	"regexp"
 	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
-var charToTokenType = map[string]TokenType{
-	"+": TokenTypePLUS,
-	"-": TokenTypeMINUS,
-	"*": TokenTypeMULTI,
-	"/": TokenTypeDIV,
-	"(": TokenTypeLPARAN,
-	")": TokenTypeRPARAN,
+var charToTokenType = map[rune]TokenType{
+	'+': TokenTypePLUS,
+	'-': TokenTypeMINUS,
+	'−': TokenTypeMINUS, // U+2212 MINUS SIGN
+	'*': TokenTypeMULTI,
+	'×': TokenTypeMULTI, // U+00D7 MULTIPLICATION SIGN
+	'⋅': TokenTypeMULTI, // U+22C5 DOT OPERATOR
+	'·': TokenTypeMULTI, // U+00B7 MIDDLE DOT
+	'∗': TokenTypeMULTI, // U+2217 ASTERISK OPERATOR
+	'/': TokenTypeDIV,
+	'÷': TokenTypeDIV, // U+00F7 DIVISION SIGN
+	'∕': TokenTypeDIV, // U+2215 DIVISION SLASH
+	'(': TokenTypeLPARAN,
+	')': TokenTypeRPARAN,
+	'.': TokenTypeDOT,
+	'[': TokenTypeLBRACKET,
+	']': TokenTypeRBRACKET,
 }
 
 // Lexer scans input text to Token.
@@ -21,17 +33,29 @@ type Lexer struct {
 	text        string
 	length      int
 	current     Token
-	currentChar string
+	currentChar rune
+	width       int
 	pos         int
+	runePos     int
+	start       Position
+	locale      Locale
 	err         error
 }
 
-// Lexer returns new Lexer with input text
-func NewLexer(text string) *Lexer {
+// Lexer returns new Lexer with input text.
+// Value of TokenTypeNUM token is the number literal without grouping separators and with "." as the decimal mark.
+func NewLexer(text string, opts ...Option) *Lexer {
 	lexer := new(Lexer)
 	lexer.text = text
 	lexer.length = len(text)
 	lexer.current = Token{}
+	options := newOptions(opts)
+	lexer.locale = options.locale
+	lexer.err = lexer.locale.validate()
+	if max := options.limits.MaxInputLength; max > 0 && lexer.length > max {
+		lexer.err = &InputLengthError{Length: lexer.length, Max: max}
+	}
+	lexer.decode()
 
 	return lexer
 }
@@ -41,6 +65,19 @@ func (l *Lexer) Token() Token {
 	return l.current
 }
 
+// Pos returns Position of the current Token in the input text.
+func (l *Lexer) Pos() Position {
+	return l.start
+}
+
+// End returns Position right after the current Token in the input text.
+func (l *Lexer) End() Position {
+	if l.current.Type == TokenTypeEOF {
+		return l.start
+	}
+	return Position{Offset: l.pos, Rune: l.runePos}
+}
+
 // Err returns error during lexical analysis.
 // If Scan method returns false, error should be checked using Err method.
 func (l *Lexer) Err() error {
@@ -50,39 +87,71 @@ func (l *Lexer) Err() error {
 // Scan returns true if converting of current characters to Token is successful.
 // Scan returns false if EOF is encountered or error happens while scanning.
 func (l *Lexer) Scan() bool {
+	if l.err != nil {
+		return false
+	}
+
+	if l.isSpace() {
+		l.skipSpace()
+	}
+
+	l.start = Position{Offset: l.pos, Rune: l.runePos}
+
 	// l.text could be ''. EOF check should be done first.
 	if l.isEOF() {
 		l.current = Token{TokenTypeEOF, ""}
 		return false
 	}
 
-	if l.pos == 0 {
-		l.currentChar = l.text[l.pos : l.pos+1]
+	if l.isStr() {
+		l.current = Token{TokenTypeVAR, l.variable()}
+		return true
 	}
 
-	if l.isSpace() {
-		l.skipSpace()
+	if l.currentChar == '`' {
+		variable, err := l.quotedVariable()
+		if err != nil {
+			l.err = err
+			return false
+		}
+		l.current = Token{TokenTypeVAR, variable}
+		return true
 	}
 
-	if l.isStr() {
-		l.current = Token{TokenTypeVAR, l.variable()}
+	if l.isInt() || (l.currentChar == l.locale.Decimal && l.isIntAt(l.pos+l.width)) {
+		number, err := l.number()
+		if err != nil {
+			l.err = err
+			return false
+		}
+		l.current = Token{TokenTypeNUM, number}
+		return true
+	}
+
+	if l.isComment() {
+		comment, err := l.comment()
+		if err != nil {
+			l.err = err
+			return false
+		}
+		l.current = Token{TokenTypeCOMMENT, comment}
 		return true
 	}
 
-	if l.isIntOrDot() {
-		l.current = Token{TokenTypeNUM, l.number()}
+	if l.currentChar == l.locale.ArgSeparator {
+		l.current = Token{TokenTypeSEP, string(l.currentChar)}
+		l.advance()
 		return true
 	}
 
-	switch l.currentChar {
-	case "+", "-", "*", "/", ")", "(":
-		l.current = Token{charToTokenType[l.currentChar], l.currentChar}
+	if tokenType, ok := charToTokenType[l.currentChar]; ok {
+		l.current = Token{tokenType, string(l.currentChar)}
 		l.advance()
 		return true
 	}
 
 	// unacceptable charater is included in text
-	l.err = errors.New(fmt.Sprintf("'%s' is not acceptable character for Lexer", l.currentChar))
+	l.err = errors.New(fmt.Sprintf("'%c' at position %s is not acceptable character for Lexer", l.currentChar, l.start))
 
 	return false
 }
@@ -93,36 +162,274 @@ func (l *Lexer) skipSpace() {
 	}
 }
 
-func (l *Lexer) number() string {
-	number := ""
-	for !l.isEOF() && l.isIntOrDot() {
-		number += l.currentChar
+// isComment reports whether comment starts at current position.
+func (l *Lexer) isComment() bool {
+	if l.currentChar == '#' {
+		return true
+	}
+	return l.currentChar == '/' && l.pos+1 < l.length && (l.text[l.pos+1] == '/' || l.text[l.pos+1] == '*')
+}
+
+// comment scans line comment until newline or block comment until "*/".
+func (l *Lexer) comment() (string, error) {
+	block := l.currentChar == '/' && l.text[l.pos+1] == '*'
+	if block {
+		end := strings.Index(l.text[l.pos+2:], "*/")
+		if end < 0 {
+			return "", errors.New(fmt.Sprintf("comment at position %s is not terminated", l.start))
+		}
+		end += l.pos + 4
+		for l.pos < end {
+			l.advance()
+		}
+		return l.text[l.start.Offset:l.pos], nil
+	}
+
+	for !l.isEOF() && l.currentChar != '\n' && l.currentChar != '\r' {
 		l.advance()
 	}
-	return number
+	return l.text[l.start.Offset:l.pos], nil
 }
 
-func (l *Lexer) variable() string {
-	variable := ""
+// number scans decimal, scientific, hexadecimal, binary and octal number literals
+// and returns the literal in the default locale. Digits may be separated by single underscores.
+func (l *Lexer) number() (string, error) {
+	literal, valid := l.scanNumber()
+
+	// A number directly followed by a digit, decimal mark or underscore is malformed. e.g. "1.2.3", "0b102"
+	if !l.isEOF() && (l.isInt() || l.currentChar == l.locale.Decimal || l.currentChar == '_') {
+		valid = false
+	}
+
+	if !valid {
+		for !l.isEOF() && (l.isInt() || l.isStr() || l.currentChar == l.locale.Decimal || l.currentChar == l.locale.Grouping) {
+			l.advance()
+		}
+		return "", errors.New(fmt.Sprintf("invalid number '%s' at position %s", l.text[l.start.Offset:l.pos], l.start))
+	}
+	return literal, nil
+}
+
+func (l *Lexer) scanNumber() (string, bool) {
+	if l.currentChar == '0' && l.pos+1 < l.length {
+		base := 0
+		switch l.text[l.pos+1] {
+		case 'x', 'X':
+			base = 16
+		case 'b', 'B':
+			base = 2
+		case 'o', 'O':
+			base = 8
+		}
+		if base != 0 {
+			l.advance()
+			l.advance()
+			valid := l.scanDigits(base, true)
+			return l.text[l.start.Offset:l.pos], valid
+		}
+	}
+
+	literal := ""
+	if l.isInt() {
+		start := l.pos
+		if !l.scanDigits(10, false) {
+			return "", false
+		}
+		literal = l.text[start:l.pos]
+		if l.locale.Grouping != 0 && l.currentChar == l.locale.Grouping && l.isIntAt(l.pos+l.width) {
+			groups, ok := l.scanGroups(literal)
+			if !ok {
+				return "", false
+			}
+			literal += groups
+		}
+	}
+
+	if l.currentChar == l.locale.Decimal {
+		l.advance()
+		literal += "."
+		if l.isInt() {
+			start := l.pos
+			if !l.scanDigits(10, false) {
+				return "", false
+			}
+			literal += l.text[start:l.pos]
+		}
+	}
+
+	if literal == "." || literal == "" {
+		return "", false
+	}
+
+	if l.currentChar == 'e' || l.currentChar == 'E' {
+		start := l.pos
+		l.advance()
+		if l.currentChar == '+' || l.currentChar == '-' {
+			l.advance()
+		}
+		if !l.scanDigits(10, false) {
+			return "", false
+		}
+		literal += l.text[start:l.pos]
+	}
+	return literal, true
+}
+
+// scanGroups consumes groups of three digits after grouping separators and returns the digits.
+// first is the digits before the first grouping separator.
+func (l *Lexer) scanGroups(first string) (string, bool) {
+	if len(first) > 3 || strings.ContainsRune(first, '_') {
+		return "", false
+	}
+
+	digits := ""
+	for l.currentChar == l.locale.Grouping && l.isIntAt(l.pos+l.width) {
+		l.advance()
+		for i := 0; i < 3; i++ {
+			if !l.isInt() {
+				return "", false
+			}
+			digits += string(l.currentChar)
+			l.advance()
+		}
+	}
+	return digits, true
+}
 
-	// First character should be alphabet or _
+// scanDigits consumes digits of base. An underscore is accepted only between digits
+// or right after a base prefix.
+func (l *Lexer) scanDigits(base int, prefixed bool) bool {
+	hasDigits := false
+	underscore := false
+	for !l.isEOF() {
+		if l.currentChar == '_' {
+			if underscore || (!hasDigits && !prefixed) {
+				return false
+			}
+			underscore = true
+		} else if l.isDigit(base) {
+			hasDigits = true
+			underscore = false
+		} else {
+			break
+		}
+		l.advance()
+	}
+	return hasDigits && !underscore
+}
+
+func (l *Lexer) variable() string {
+	// First character should be letter or _
 	if !l.isEOF() && l.isStr() {
-		variable += l.currentChar
 		l.advance()
 	}
 
-	for !l.isEOF() && (l.isStr() || l.isInt()) {
-		variable += l.currentChar
+	for !l.isEOF() && (l.isStr() || l.isIdentPart()) {
 		l.advance()
 	}
-	return variable
+	return l.text[l.start.Offset:l.pos]
+}
+
+// quotedVariable scans variable name enclosed in backticks and returns the name without quotes.
+// Backslash starts escape sequence: \\, \`, \n, \t or \uXXXX, which is not a surrogate.
+func (l *Lexer) quotedVariable() (string, error) {
+	// skip opening backtick
+	l.advance()
+
+	variable := make([]rune, 0)
+	for !l.isEOF() && l.currentChar != '`' {
+		if l.currentChar != '\\' {
+			variable = append(variable, l.currentChar)
+			l.advance()
+			continue
+		}
+
+		escapePos := Position{Offset: l.pos, Rune: l.runePos}
+		l.advance()
+		switch l.currentChar {
+		case '\\', '`':
+			variable = append(variable, l.currentChar)
+		case 'n':
+			variable = append(variable, '\n')
+		case 't':
+			variable = append(variable, '\t')
+		case 'u':
+			if l.pos+5 > l.length {
+				return "", errors.New(fmt.Sprintf("invalid escape sequence at position %s", escapePos))
+			}
+			code, err := strconv.ParseUint(l.text[l.pos+1:l.pos+5], 16, 32)
+			// Surrogates are not characters, and would be replaced with U+FFFD in the name.
+			if err != nil || !utf8.ValidRune(rune(code)) {
+				return "", errors.New(fmt.Sprintf("invalid escape sequence at position %s", escapePos))
+			}
+			variable = append(variable, rune(code))
+			for i := 0; i < 4; i++ {
+				l.advance()
+			}
+		default:
+			return "", errors.New(fmt.Sprintf("invalid escape sequence at position %s", escapePos))
+		}
+		l.advance()
+	}
+
+	if l.isEOF() {
+		return "", errors.New(fmt.Sprintf("quoted variable at position %s is not terminated", l.start))
+	}
+	// skip closing backtick
+	l.advance()
+
+	if len(variable) == 0 {
+		return "", errors.New(fmt.Sprintf("empty quoted variable at position %s", l.start))
+	}
+	return string(variable), nil
+}
+
+// quoteVariable returns variable name which Lexer reads back as the same name.
+// Names which are not plain identifiers are enclosed in backticks.
+func quoteVariable(name string) string {
+	if isIdentifier(name) {
+		return name
+	}
+
+	quoted := []rune{'`'}
+	for _, char := range name {
+		switch char {
+		case '\\', '`':
+			quoted = append(quoted, '\\', char)
+		case '\n':
+			quoted = append(quoted, '\\', 'n')
+		case '\t':
+			quoted = append(quoted, '\\', 't')
+		default:
+			quoted = append(quoted, char)
+		}
+	}
+	return string(append(quoted, '`'))
+}
+
+func isIdentifier(name string) bool {
+	lexer := NewLexer(name)
+	if !lexer.isStr() {
+		return false
+	}
+	lexer.variable()
+	return lexer.isEOF()
 }
 
 func (l *Lexer) advance() {
-	l.pos++
-	if !l.isEOF() {
-		l.currentChar = l.text[l.pos : l.pos+1]
+	l.pos += l.width
+	l.runePos++
+	l.decode()
+}
+
+// decode reads the rune at current position. Invalid UTF-8 is read as utf8.RuneError.
+func (l *Lexer) decode() {
+	if l.isEOF() {
+		l.currentChar = 0
+		l.width = 0
+		return
 	}
+	l.currentChar, l.width = utf8.DecodeRuneInString(l.text[l.pos:])
 }
 
 func (l *Lexer) isEOF() bool {
@@ -130,27 +437,33 @@ func (l *Lexer) isEOF() bool {
 }
 
 func (l *Lexer) isSpace() bool {
-	return l.currentChar == " "
+	return unicode.IsSpace(l.currentChar)
 }
 
-func (l *Lexer) isIntOrDot() bool {
-	if l.currentChar == "." {
-		return true
+func (l *Lexer) isDigit(base int) bool {
+	if l.currentChar >= utf8.RuneSelf {
+		return false
 	}
-	return l.isInt()
-}
-
-func (l *Lexer) isInt() bool {
-	if _, err := strconv.Atoi(l.currentChar); err != nil {
+	if _, err := strconv.ParseUint(string(l.currentChar), base, 8); err != nil {
 		return false
 	}
 	return true
 }
 
+func (l *Lexer) isInt() bool {
+	return '0' <= l.currentChar && l.currentChar <= '9'
+}
+
+// isIntAt reports whether the byte at offset is a digit.
+func (l *Lexer) isIntAt(offset int) bool {
+	return offset < l.length && '0' <= l.text[offset] && l.text[offset] <= '9'
+}
+
 func (l *Lexer) isStr() bool {
-	isStr, err := regexp.MatchString("^[a-zA-Z_]$", l.currentChar)
-	if err != nil {
-		return false
-	}
-	return isStr
+	return l.currentChar == '_' || unicode.IsLetter(l.currentChar)
+}
+
+// isIdentPart reports whether current character can follow the first character of identifier.
+func (l *Lexer) isIdentPart() bool {
+	return unicode.IsDigit(l.currentChar) || unicode.In(l.currentChar, unicode.Mn, unicode.Mc)
 }
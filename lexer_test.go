@@ -51,6 +51,81 @@ func TestLexer(t *testing.T) {
 				Token{TokenTypeNUM, "1"},
 			},
 		},
+		{
+			"1e-6+6.02E23*0xFF/0b1010-0o17+1_000_000+.5",
+			[]Token{
+				Token{TokenTypeNUM, "1e-6"},
+				Token{TokenTypePLUS, "+"},
+				Token{TokenTypeNUM, "6.02E23"},
+				Token{TokenTypeMULTI, "*"},
+				Token{TokenTypeNUM, "0xFF"},
+				Token{TokenTypeDIV, "/"},
+				Token{TokenTypeNUM, "0b1010"},
+				Token{TokenTypeMINUS, "-"},
+				Token{TokenTypeNUM, "0o17"},
+				Token{TokenTypePLUS, "+"},
+				Token{TokenTypeNUM, "1_000_000"},
+				Token{TokenTypePLUS, "+"},
+				Token{TokenTypeNUM, ".5"},
+			},
+		},
+		{
+			"2π×r÷größe−価格·x_1",
+			[]Token{
+				Token{TokenTypeNUM, "2"},
+				Token{TokenTypeVAR, "π"},
+				Token{TokenTypeMULTI, "×"},
+				Token{TokenTypeVAR, "r"},
+				Token{TokenTypeDIV, "÷"},
+				Token{TokenTypeVAR, "größe"},
+				Token{TokenTypeMINUS, "−"},
+				Token{TokenTypeVAR, "価格"},
+				Token{TokenTypeMULTI, "·"},
+				Token{TokenTypeVAR, "x_1"},
+			},
+		},
+		{
+			"`Unit Price (USD)` * `net-revenue` + `a\\`b\\\\c\\u00e9`",
+			[]Token{
+				Token{TokenTypeVAR, "Unit Price (USD)"},
+				Token{TokenTypeMULTI, "*"},
+				Token{TokenTypeVAR, "net-revenue"},
+				Token{TokenTypePLUS, "+"},
+				Token{TokenTypeVAR, "a`b\\cé"},
+			},
+		},
+		{
+			"order.customer.`tier name` + items[2].price*.5",
+			[]Token{
+				Token{TokenTypeVAR, "order"},
+				Token{TokenTypeDOT, "."},
+				Token{TokenTypeVAR, "customer"},
+				Token{TokenTypeDOT, "."},
+				Token{TokenTypeVAR, "tier name"},
+				Token{TokenTypePLUS, "+"},
+				Token{TokenTypeVAR, "items"},
+				Token{TokenTypeLBRACKET, "["},
+				Token{TokenTypeNUM, "2"},
+				Token{TokenTypeRBRACKET, "]"},
+				Token{TokenTypeDOT, "."},
+				Token{TokenTypeVAR, "price"},
+				Token{TokenTypeMULTI, "*"},
+				Token{TokenTypeNUM, ".5"},
+			},
+		},
+		{
+			"# price\n\t32 /* base */ +\u00a0x // tail\r\n/ 2",
+			[]Token{
+				Token{TokenTypeCOMMENT, "# price"},
+				Token{TokenTypeNUM, "32"},
+				Token{TokenTypeCOMMENT, "/* base */"},
+				Token{TokenTypePLUS, "+"},
+				Token{TokenTypeVAR, "x"},
+				Token{TokenTypeCOMMENT, "// tail"},
+				Token{TokenTypeDIV, "/"},
+				Token{TokenTypeNUM, "2"},
+			},
+		},
 		{
 			"",
 			[]Token{},
@@ -77,3 +152,124 @@ func TestLexer(t *testing.T) {
 		}
 	}
 }
+
+func TestLexerInvalidNumber(t *testing.T) {
+	assert := assert.New(t)
+	var testdata = []struct {
+		input string
+		err   string
+	}{
+		{"1.2.3", "invalid number '1.2.3' at position 0"},
+		{"2+0b102", "invalid number '0b102' at position 2"},
+		{"1e+", "invalid number '1e+' at position 0"},
+		{"0x", "invalid number '0x' at position 0"},
+		{"1__000", "invalid number '1__000' at position 0"},
+		{"1_", "invalid number '1_' at position 0"},
+		{"3 * 1._5", "invalid number '1._5' at position 4"},
+		{"größe + 1.2.3", "invalid number '1.2.3' at position 8 (byte 10)"},
+	}
+
+	for _, data := range testdata {
+		lexer := NewLexer(data.input)
+		for lexer.Scan() {
+		}
+
+		if assert.Error(lexer.Err(), data.input) {
+			assert.Equal(data.err, lexer.Err().Error())
+		}
+	}
+}
+
+func TestLexerPos(t *testing.T) {
+	assert := assert.New(t)
+
+	lexer := NewLexer("größe × 2")
+	var positions []Position
+	for lexer.Scan() {
+		positions = append(positions, lexer.Pos())
+	}
+
+	assert.Equal(
+		[]Position{
+			Position{Offset: 0, Rune: 0},
+			Position{Offset: 8, Rune: 6},
+			Position{Offset: 11, Rune: 8},
+		},
+		positions,
+	)
+	assert.Equal(Position{Offset: 12, Rune: 9}, lexer.Pos())
+
+	lexer = NewLexer("1 + größe $")
+	for lexer.Scan() {
+	}
+	assert.EqualError(lexer.Err(), "'$' at position 10 (byte 12) is not acceptable character for Lexer")
+}
+
+func TestLexerInvalidQuotedVariable(t *testing.T) {
+	assert := assert.New(t)
+	var testdata = []struct {
+		input string
+		err   string
+	}{
+		{"1 + `price", "quoted variable at position 4 is not terminated"},
+		{"1 /* price", "comment at position 2 is not terminated"},
+		{"``", "empty quoted variable at position 0"},
+		{"`a\\q`", "invalid escape sequence at position 2"},
+		{"`a\\u00`", "invalid escape sequence at position 2"},
+		{"`a\\uD800`", "invalid escape sequence at position 2"},
+		{"x + `\\udfff`", "invalid escape sequence at position 5"},
+	}
+
+	for _, data := range testdata {
+		lexer := NewLexer(data.input)
+		for lexer.Scan() {
+		}
+
+		if assert.Error(lexer.Err(), data.input) {
+			assert.Equal(data.err, lexer.Err().Error())
+		}
+	}
+}
+
+func TestLexerWithLocale(t *testing.T) {
+	assert := assert.New(t)
+
+	lexer := NewLexer("1.234.567,89 + 0,5; items[2].price ,5", WithLocale(DecimalCommaLocale))
+	tokens := make([]Token, 0)
+	for lexer.Scan() {
+		tokens = append(tokens, lexer.Token())
+	}
+	assert.NoError(lexer.Err())
+	assert.Equal(
+		[]Token{
+			Token{TokenTypeNUM, "1234567.89"},
+			Token{TokenTypePLUS, "+"},
+			Token{TokenTypeNUM, "0.5"},
+			Token{TokenTypeSEP, ";"},
+			Token{TokenTypeVAR, "items"},
+			Token{TokenTypeLBRACKET, "["},
+			Token{TokenTypeNUM, "2"},
+			Token{TokenTypeRBRACKET, "]"},
+			Token{TokenTypeDOT, "."},
+			Token{TokenTypeVAR, "price"},
+			Token{TokenTypeNUM, ".5"},
+		},
+		tokens,
+	)
+
+	for input, err := range map[string]string{
+		"1.5":       "invalid number '1.5' at position 0",
+		"1234.567":  "invalid number '1234.567' at position 0",
+		"1.234,5,6": "invalid number '1.234,5,6' at position 0",
+		"1.2345":    "invalid number '1.2345' at position 0",
+	} {
+		lexer := NewLexer(input, WithLocale(DecimalCommaLocale))
+		for lexer.Scan() {
+		}
+		assert.EqualError(lexer.Err(), err, input)
+	}
+
+	lexer = NewLexer("1", WithLocale(Locale{Decimal: '.', Grouping: '.', ArgSeparator: ','}))
+	assert.False(lexer.Scan())
+	assert.Error(lexer.Err())
+}
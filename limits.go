@@ -0,0 +1,63 @@
+package goculator
+
+import "fmt"
+
+// Limits restricts resources used by a formula, so that a hostile formula cannot exhaust memory or stack.
+// Zero means no limit.
+type Limits struct {
+	// MaxInputLength is the maximum length of input text in bytes.
+	MaxInputLength int
+	// MaxDepth is the maximum nesting depth of parentheses, unary operators and indexes.
+	MaxDepth int
+	// MaxNodes is the maximum number of nodes of the parsed expression,
+	// i.e. numbers, variables and operators.
+	MaxNodes int
+	// MaxSteps is the maximum number of evaluation steps. A step is the evaluation of a node
+	// of the parsed expression, so every way of evaluation takes the same number of steps.
+	MaxSteps int
+}
+
+// DefaultLimits limits the nesting depth and the number of nodes to protect the goroutine stack,
+// because parsing, evaluation and the other operations on the parsed expression are recursive.
+// A chain of binary operators is not nesting, but it is as deep as its length in the parsed expression,
+// so the number of nodes bounds its length.
+var DefaultLimits = Limits{MaxDepth: 1000, MaxNodes: 1000000}
+
+// InputLengthError is returned if input text is longer than Limits.MaxInputLength.
+type InputLengthError struct {
+	Length int
+	Max    int
+}
+
+func (e *InputLengthError) Error() string {
+	return fmt.Sprintf("input length %d exceeds limit %d", e.Length, e.Max)
+}
+
+// DepthLimitError is returned if nesting of a formula is deeper than Limits.MaxDepth.
+type DepthLimitError struct {
+	Max int
+	Pos Position
+}
+
+func (e *DepthLimitError) Error() string {
+	return fmt.Sprintf("nesting depth exceeds limit %d at position %s", e.Max, e.Pos)
+}
+
+// NodeLimitError is returned if a formula has more nodes than Limits.MaxNodes.
+type NodeLimitError struct {
+	Max int
+	Pos Position
+}
+
+func (e *NodeLimitError) Error() string {
+	return fmt.Sprintf("number of nodes exceeds limit %d at position %s", e.Max, e.Pos)
+}
+
+// StepLimitError is returned if evaluation takes more steps than Limits.MaxSteps.
+type StepLimitError struct {
+	Max int
+}
+
+func (e *StepLimitError) Error() string {
+	return fmt.Sprintf("evaluation steps exceed limit %d", e.Max)
+}
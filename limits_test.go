@@ -0,0 +1,95 @@
+package goculator
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestLimits(t *testing.T) {
+	assert := assert.New(t)
+
+	// default limits protect the goroutine stack from deep nesting
+	_, err := Parse(strings.Repeat("(", 100000) + "1" + strings.Repeat(")", 100000))
+	if assert.IsType(&DepthLimitError{}, err) {
+		assert.EqualError(err, "nesting depth exceeds limit 1000 at position 1000")
+	}
+
+	_, err = Parse(strings.Repeat("-", 100000) + "1")
+	assert.IsType(&DepthLimitError{}, err)
+
+	// a flat chain of operators is not nesting, but its length is bounded by the number of nodes
+	chain, err := Parse("1" + strings.Repeat("+1", 100000))
+	if assert.NoError(err) {
+		result, err := chain.Eval(nil)
+		assert.NoError(err)
+		assert.Equal(float64(100001), result)
+	}
+	_, err = New("1" + strings.Repeat("+1", 3000000)).Go()
+	if assert.IsType(&NodeLimitError{}, err) {
+		assert.EqualError(err, "number of nodes exceeds limit 1000000 at position 1000000")
+	}
+
+	_, err = Parse("a[b[c[d]]]", WithLimits(Limits{MaxDepth: 3}))
+	assert.IsType(&DepthLimitError{}, err)
+
+	_, err = Parse("1 + 2 * 3", WithLimits(Limits{MaxInputLength: 8}))
+	if assert.IsType(&InputLengthError{}, err) {
+		assert.EqualError(err, "input length 9 exceeds limit 8")
+	}
+
+	_, err = Parse("1 + 2 * 3 - x", WithLimits(Limits{MaxNodes: 6}))
+	if assert.IsType(&NodeLimitError{}, err) {
+		assert.EqualError(err, "number of nodes exceeds limit 6 at position 12")
+	}
+
+	_, err = Parse("1 + 2 * 3 - x", WithLimits(Limits{MaxNodes: 7}))
+	assert.NoError(err)
+
+	expr, err := Parse("1 + 2 * 3 - x", WithLimits(Limits{MaxSteps: 6}))
+	assert.NoError(err)
+	context := NewDefaultContext(map[string]float64{"x": 1})
+
+	_, err = expr.Eval(context)
+	if assert.IsType(&StepLimitError{}, err) {
+		assert.EqualError(err, "evaluation steps exceed limit 6")
+	}
+	_, err = expr.Compile().Run(context)
+	assert.IsType(&StepLimitError{}, err)
+
+	expr, _ = Parse("1 + 2 * 3 - x", WithLimits(Limits{MaxSteps: 7}))
+	result, err := expr.Eval(context)
+	assert.NoError(err)
+	assert.Equal(float64(6), result)
+	_, err = expr.Compile().Run(context)
+	assert.NoError(err)
+
+	// every way of evaluation takes one step for each node, including unary plus and number literal indexes
+	columns := map[string][]float64{"x[0]": {2}, "y": {3}}
+	context = NewDefaultContext(map[string]float64{"x[0]": 2, "y": 3})
+	for _, max := range []int{7, 8} {
+		expr, err := Parse("+x[0] * -(1 + y)", WithLimits(Limits{MaxSteps: max}))
+		if !assert.NoError(err) {
+			continue
+		}
+		var errs []error
+		_, err = expr.Eval(context)
+		errs = append(errs, err)
+		_, err = expr.Compile().Run(context)
+		errs = append(errs, err)
+		_, err = expr.CompileClosure().Run(context)
+		errs = append(errs, err)
+		_, err = expr.EvalBatch(columns, make([]float64, 1))
+		errs = append(errs, err)
+		for i, err := range errs {
+			if max < 8 {
+				assert.IsType(&StepLimitError{}, err, "backend %d with MaxSteps %d", i, max)
+			} else {
+				assert.NoError(err, "backend %d with MaxSteps %d", i, max)
+			}
+		}
+	}
+
+	_, err = New("1 + 2", WithLimits(Limits{MaxInputLength: 3})).Go()
+	assert.IsType(&InputLengthError{}, err)
+}
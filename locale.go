@@ -0,0 +1,72 @@
+package goculator
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Locale is the set of separators used in number literals and argument lists.
+type Locale struct {
+	// Decimal is the decimal mark.
+	Decimal rune
+	// Grouping separates groups of three digits in the integer part. Zero means no grouping.
+	Grouping rune
+	// ArgSeparator separates arguments.
+	ArgSeparator rune
+}
+
+// DefaultLocale uses "." as the decimal mark and "," as the argument separator without grouping.
+var DefaultLocale = Locale{Decimal: '.', ArgSeparator: ','}
+
+// DecimalCommaLocale uses "," as the decimal mark, "." as the grouping separator
+// and ";" as the argument separator, e.g. 1.234,56.
+var DecimalCommaLocale = Locale{Decimal: ',', Grouping: '.', ArgSeparator: ';'}
+
+func (l Locale) validate() error {
+	if l.Decimal == 0 || l.ArgSeparator == 0 || l.Decimal == l.Grouping || l.Decimal == l.ArgSeparator || l.Grouping == l.ArgSeparator {
+		return errors.New(fmt.Sprintf("invalid locale: decimal mark %q, grouping separator %q and argument separator %q should be different", l.Decimal, l.Grouping, l.ArgSeparator))
+	}
+	if isDigitOrLetter(l.Decimal) || isDigitOrLetter(l.Grouping) || isDigitOrLetter(l.ArgSeparator) {
+		return errors.New("invalid locale: separators should not be digits or letters")
+	}
+	return nil
+}
+
+// Format returns f as string with the separators of Locale.
+// prec is the number of digits after the decimal mark. -1 uses the smallest number of digits necessary.
+func (l Locale) Format(f float64, prec int) string {
+	s := strconv.FormatFloat(f, 'f', prec, 64)
+	if s == "NaN" || s == "+Inf" || s == "-Inf" {
+		return s
+	}
+
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+
+	integer, fraction := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		integer, fraction = s[:i], s[i+1:]
+	}
+
+	if l.Grouping != 0 {
+		grouped := integer[:(len(integer)-1)%3+1]
+		for i := len(grouped); i < len(integer); i += 3 {
+			grouped += string(l.Grouping) + integer[i:i+3]
+		}
+		integer = grouped
+	}
+
+	if fraction == "" {
+		return sign + integer
+	}
+	return sign + integer + string(l.Decimal) + fraction
+}
+
+func isDigitOrLetter(r rune) bool {
+	return r == '_' || unicode.IsDigit(r) || unicode.IsLetter(r)
+}
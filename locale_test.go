@@ -0,0 +1,30 @@
+package goculator
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+func TestLocaleFormat(t *testing.T) {
+	assert := assert.New(t)
+	var testdata = []struct {
+		locale Locale
+		input  float64
+		prec   int
+		result string
+	}{
+		{DefaultLocale, 1234567.891, -1, "1234567.891"},
+		{DefaultLocale, 0.5, 2, "0.50"},
+		{DecimalCommaLocale, 1234567.891, 2, "1.234.567,89"},
+		{DecimalCommaLocale, -1234.5, -1, "-1.234,5"},
+		{DecimalCommaLocale, 123, -1, "123"},
+		{DecimalCommaLocale, 0.25, -1, "0,25"},
+		{Locale{Decimal: '.', Grouping: ',', ArgSeparator: ';'}, 12345678, 0, "12,345,678"},
+		{DecimalCommaLocale, math.Inf(-1), 2, "-Inf"},
+	}
+
+	for _, data := range testdata {
+		assert.Equal(data.result, data.locale.Format(data.input, data.prec))
+	}
+}
@@ -0,0 +1,37 @@
+package goculator
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+)
+
+// parseNumber converts number literal scanned by Lexer to float64.
+// Hexadecimal, binary and octal literals are integers of any size which float64 can represent.
+func parseNumber(literal string) (float64, error) {
+	if len(literal) > 2 && literal[0] == '0' {
+		switch literal[1] {
+		case 'x', 'X', 'b', 'B', 'o', 'O':
+			i, ok := new(big.Int).SetString(literal, 0)
+			if !ok {
+				return 0, errors.New(fmt.Sprintf("invalid number '%s'", literal))
+			}
+			result, _ := new(big.Float).SetInt(i).Float64()
+			if math.IsInf(result, 0) {
+				return 0, errors.New(fmt.Sprintf("number '%s' is out of range", literal))
+			}
+			return result, nil
+		}
+	}
+
+	result, err := strconv.ParseFloat(literal, 64)
+	if err != nil {
+		if numErr, ok := err.(*strconv.NumError); ok && numErr.Err == strconv.ErrRange {
+			return 0, errors.New(fmt.Sprintf("number '%s' is out of range", literal))
+		}
+		return 0, errors.New(fmt.Sprintf("invalid number '%s'", literal))
+	}
+	return result, nil
+}
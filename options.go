@@ -0,0 +1,32 @@
+package goculator
+
+// Option configures Lexer and Calculator.
+type Option func(*options)
+
+type options struct {
+	locale Locale
+	limits Limits
+	policy Policy
+}
+
+func newOptions(opts []Option) options {
+	o := options{locale: DefaultLocale, limits: DefaultLimits}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithLocale sets Locale which decides the decimal mark, grouping separator and argument separator.
+func WithLocale(locale Locale) Option {
+	return func(o *options) {
+		o.locale = locale
+	}
+}
+
+// WithLimits sets Limits of parsing and evaluation. Default is DefaultLimits.
+func WithLimits(limits Limits) Option {
+	return func(o *options) {
+		o.limits = limits
+	}
+}
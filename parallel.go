@@ -0,0 +1,146 @@
+package goculator
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// ParallelOptions configures RunParallel.
+type ParallelOptions struct {
+	// Workers is the number of goroutines which evaluate Program. Zero means runtime.GOMAXPROCS(0).
+	Workers int
+	// Ordered sends results in the order of contexts. Otherwise results are sent as soon as they are evaluated.
+	Ordered bool
+	// StopOnError stops evaluation after the first error is sent.
+	StopOnError bool
+}
+
+// Result is the result of evaluation of the Index-th Context, starting at 0.
+type Result struct {
+	Index int
+	Value float64
+	Err   error
+}
+
+// parallelJob is a Context to be evaluated by a worker.
+type parallelJob struct {
+	index int
+	vars  Context
+}
+
+// RunParallel evaluates Program with every Context received from contexts by a pool of workers,
+// and sends the results to the returned channel, which is closed when all results are sent.
+// Program is immutable, so the workers share it. Contexts must be safe for concurrent use
+// if the same Context is sent more than once.
+//
+// The caller must close contexts and receive all results, or cancel ctx to stop evaluation early.
+// When evaluation stops by ctx or StopOnError, remaining contexts are received and discarded
+// until contexts is closed, so that the sender is not blocked.
+func (p *Program) RunParallel(ctx context.Context, contexts <-chan Context, options ParallelOptions) <-chan Result {
+	workers := options.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	jobs := make(chan parallelJob, workers)
+	results := make(chan Result, workers)
+	out := make(chan Result, workers)
+
+	go func() {
+		defer close(jobs)
+		index := 0
+		for vars := range contexts {
+			select {
+			case jobs <- parallelJob{index: index, vars: vars}:
+				index++
+			case <-ctx.Done():
+				for range contexts {
+				}
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				value, err := p.RunContext(ctx, job.vars)
+				select {
+				case results <- Result{Index: job.index, Value: value, Err: err}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	go func() {
+		defer close(out)
+		c := collector{out: out, ctx: ctx, options: options, pending: make(map[int]Result)}
+		for result := range results {
+			if !c.collect(result) {
+				break
+			}
+		}
+		// Receives results of workers until they stop, so that no worker is blocked.
+		cancel()
+		for range results {
+		}
+	}()
+	return out
+}
+
+// collector sends results of workers to out.
+type collector struct {
+	out     chan<- Result
+	ctx     context.Context
+	options ParallelOptions
+	// next is the index of the next result to be sent if Ordered.
+	next int
+	// pending is the results which wait for the results of smaller indexes if Ordered.
+	pending map[int]Result
+}
+
+// collect sends result, or keeps it until its turn if Ordered.
+// It reports false if evaluation should stop.
+func (c *collector) collect(result Result) bool {
+	if !c.options.Ordered {
+		return c.send(result)
+	}
+
+	c.pending[result.Index] = result
+	for {
+		result, ok := c.pending[c.next]
+		if !ok {
+			return true
+		}
+		delete(c.pending, c.next)
+		c.next++
+		if !c.send(result) {
+			return false
+		}
+	}
+}
+
+// send sends result to out. It reports false if ctx is done or evaluation stops on the error of result.
+func (c *collector) send(result Result) bool {
+	if result.Err != nil && c.ctx.Err() != nil {
+		// The error is caused by cancellation of ctx, which the caller knows.
+		return false
+	}
+	select {
+	case c.out <- result:
+		return result.Err == nil || !c.options.StopOnError
+	case <-c.ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,111 @@
+package goculator
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"sort"
+	"testing"
+)
+
+// sendContexts sends n contexts whose x is the index to the returned channel.
+// The context of each index in failures has no x.
+func sendContexts(n int, failures ...int) <-chan Context {
+	contexts := make(chan Context)
+	go func() {
+		defer close(contexts)
+		for i := 0; i < n; i++ {
+			values := map[string]float64{"x": float64(i)}
+			for _, failure := range failures {
+				if i == failure {
+					values = map[string]float64{}
+				}
+			}
+			contexts <- NewDefaultContext(values)
+		}
+	}()
+	return contexts
+}
+
+func TestRunParallel(t *testing.T) {
+	assert := assert.New(t)
+
+	expr, _ := Parse("x * 2 + 1")
+	program := expr.Compile()
+
+	var results []Result
+	for result := range program.RunParallel(context.Background(), sendContexts(1000), ParallelOptions{Workers: 8, Ordered: true}) {
+		results = append(results, result)
+	}
+	if assert.Len(results, 1000) {
+		for i, result := range results {
+			assert.Equal(Result{Index: i, Value: float64(i*2 + 1)}, result)
+		}
+	}
+
+	results = nil
+	for result := range program.RunParallel(context.Background(), sendContexts(1000, 10), ParallelOptions{}) {
+		results = append(results, result)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Index < results[j].Index })
+	if assert.Len(results, 1000) {
+		for i, result := range results {
+			assert.Equal(i, result.Index)
+			if i == 10 {
+				assert.Error(result.Err)
+				continue
+			}
+			assert.Equal(float64(i*2+1), result.Value)
+		}
+	}
+}
+
+func TestRunParallelStopOnError(t *testing.T) {
+	assert := assert.New(t)
+
+	expr, _ := Parse("x * 2 + 1")
+	program := expr.Compile()
+
+	var results []Result
+	options := ParallelOptions{Workers: 4, Ordered: true, StopOnError: true}
+	for result := range program.RunParallel(context.Background(), sendContexts(1000, 500, 700), options) {
+		results = append(results, result)
+	}
+	if assert.Len(results, 501) {
+		for i, result := range results[:500] {
+			assert.Equal(Result{Index: i, Value: float64(i*2 + 1)}, result)
+		}
+		assert.Equal(500, results[500].Index)
+		assert.EqualError(results[500].Err, "no value for key 'x'")
+	}
+
+	results = nil
+	options = ParallelOptions{Workers: 4, StopOnError: true}
+	for result := range program.RunParallel(context.Background(), sendContexts(1000, 500), options) {
+		results = append(results, result)
+	}
+	assert.Error(results[len(results)-1].Err)
+	for _, result := range results[:len(results)-1] {
+		assert.NoError(result.Err)
+	}
+}
+
+func TestRunParallelCancel(t *testing.T) {
+	assert := assert.New(t)
+
+	expr, _ := Parse("x")
+	program := expr.Compile()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	count := 0
+	for result := range program.RunParallel(ctx, sendContexts(100000), ParallelOptions{Workers: 4, Ordered: true}) {
+		assert.NoError(result.Err)
+		count++
+		if count == 10 {
+			cancel()
+		}
+	}
+	assert.True(count < 100000)
+	assert.True(errors.Is(ctx.Err(), context.Canceled))
+}
@@ -0,0 +1,262 @@
+package goculator
+
+import (
+	"errors"
+	"fmt"
+)
+
+// parser builds the abstract syntax tree from Token of Lexer using recursive descent.
+type parser struct {
+	lexer  *Lexer
+	limits Limits
+	depth  int
+	nodes  int
+}
+
+func (p *parser) parse() (node, error) {
+	if err := p.scan(); err != nil {
+		return nil, err
+	}
+
+	if p.currentToken().Type == TokenTypeEOF {
+		return nil, nil
+	}
+
+	root, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.currentToken().Type != TokenTypeEOF {
+		return nil, errors.New(fmt.Sprintf("unexpected token '%s' at position %s", p.currentToken().Value, p.lexer.Pos()))
+	}
+	return root, nil
+}
+
+// countNode counts a new node at pos and returns error if the number of nodes exceeds the limit.
+func (p *parser) countNode(pos Position) error {
+	p.nodes++
+	if p.limits.MaxNodes > 0 && p.nodes > p.limits.MaxNodes {
+		return &NodeLimitError{Max: p.limits.MaxNodes, Pos: pos}
+	}
+	return nil
+}
+
+func (p *parser) eat(TokenType TokenType) error {
+	if p.currentToken().Type != TokenType {
+		return errors.New(
+			fmt.Sprintf(
+				"expected token type %s is not matching currunt token type %s at position %s",
+				TokenType,
+				p.currentToken().Type,
+				p.lexer.Pos(),
+			),
+		)
+	}
+	return p.scan()
+}
+
+// scan moves to the next Token skipping comments.
+func (p *parser) scan() error {
+	for p.lexer.Scan() && p.currentToken().Type == TokenTypeCOMMENT {
+	}
+	return p.lexer.Err()
+}
+
+func (p *parser) currentToken() Token {
+	return p.lexer.Token()
+}
+
+// path executes grammar below and return variableNode and error.
+// grammar: VAR (DOT VAR | LBRACKET expr RBRACKET)*
+func (p *parser) path() (node, error) {
+	variable := &variableNode{s: Span{Start: p.lexer.Pos()}}
+	if err := p.countNode(variable.s.Start); err != nil {
+		return nil, err
+	}
+
+	token := p.currentToken()
+	variable.s.End = p.lexer.End()
+	if err := p.eat(TokenTypeVAR); err != nil {
+		return nil, err
+	}
+	variable.path = []pathElementNode{pathElementNode{name: token.Value}}
+
+	for {
+		switch p.currentToken().Type {
+		case TokenTypeDOT:
+			if err := p.eat(TokenTypeDOT); err != nil {
+				return nil, err
+			}
+			token := p.currentToken()
+			variable.s.End = p.lexer.End()
+			if err := p.eat(TokenTypeVAR); err != nil {
+				return nil, err
+			}
+			variable.path = append(variable.path, pathElementNode{name: token.Value})
+		case TokenTypeLBRACKET:
+			if err := p.eat(TokenTypeLBRACKET); err != nil {
+				return nil, err
+			}
+			index, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			variable.s.End = p.lexer.End()
+			if err := p.eat(TokenTypeRBRACKET); err != nil {
+				return nil, err
+			}
+			variable.path = append(variable.path, pathElementNode{index: index})
+		default:
+			return variable, nil
+		}
+	}
+}
+
+// factor executes grammar below and return node and error.
+// grammar: (PLUS|MINUS) factor | NUM | path | LPARAN expr RPARAN
+func (p *parser) factor() (node, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.limits.MaxDepth > 0 && p.depth > p.limits.MaxDepth {
+		return nil, &DepthLimitError{Max: p.limits.MaxDepth, Pos: p.lexer.Pos()}
+	}
+
+	token := p.currentToken()
+
+	// For unary operator case
+	if p.isCurrentTokenPlusOrMinus() {
+		start := p.lexer.Pos()
+		if err := p.countNode(start); err != nil {
+			return nil, err
+		}
+		if err := p.eat(token.Type); err != nil {
+			return nil, err
+		}
+		operand, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op: token.Type, operand: operand, s: Span{Start: start, End: operand.span().End}}, nil
+	}
+
+	// For parantheses case
+	if token.Type == TokenTypeLPARAN {
+		if err := p.eat(TokenTypeLPARAN); err != nil {
+			return nil, err
+		}
+		result, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.eat(TokenTypeRPARAN); err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+
+	// For variable case
+	if token.Type == TokenTypeVAR {
+		return p.path()
+	}
+
+	// For number case
+	number := &numberNode{literal: token.Value, s: Span{Start: p.lexer.Pos(), End: p.lexer.End()}}
+	if err := p.countNode(number.s.Start); err != nil {
+		return nil, err
+	}
+	if err := p.eat(TokenTypeNUM); err != nil {
+		return nil, err
+	}
+
+	value, err := parseNumber(token.Value)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("%s at position %s", err, number.s.Start))
+	}
+	number.value = value
+	return number, nil
+}
+
+// term executes grammar below and return node and error.
+// grammar: factor((MULTI|DIV)factor)*
+func (p *parser) term() (node, error) {
+	result, err := p.factor()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isCurrentTokenMultiOrDiv() {
+		op := p.currentToken()
+		opPos := p.lexer.Pos()
+		if err := p.countNode(opPos); err != nil {
+			return nil, err
+		}
+		if err := p.eat(op.Type); err != nil {
+			return nil, err
+		}
+
+		right, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+
+		result = newBinaryNode(op.Type, result, right, opPos)
+	}
+
+	return result, nil
+}
+
+// expr executes grammar below and return node and error.
+// grammar: term((PLUS|MINUS)term)*
+func (p *parser) expr() (node, error) {
+	result, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isCurrentTokenPlusOrMinus() {
+		op := p.currentToken()
+		opPos := p.lexer.Pos()
+		if err := p.countNode(opPos); err != nil {
+			return nil, err
+		}
+		if err := p.eat(op.Type); err != nil {
+			return nil, err
+		}
+
+		right, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+
+		result = newBinaryNode(op.Type, result, right, opPos)
+	}
+
+	return result, nil
+}
+
+func newBinaryNode(op TokenType, left, right node, opPos Position) *binaryNode {
+	return &binaryNode{
+		op:    op,
+		left:  left,
+		right: right,
+		s:     Span{Start: left.span().Start, End: right.span().End},
+		opPos: opPos,
+	}
+}
+
+func (p *parser) isCurrentTokenPlusOrMinus() bool {
+	cTokenType := p.currentToken().Type
+	if cTokenType == TokenTypePLUS || cTokenType == TokenTypeMINUS {
+		return true
+	}
+	return false
+}
+
+func (p *parser) isCurrentTokenMultiOrDiv() bool {
+	cTokenType := p.currentToken().Type
+	if cTokenType == TokenTypeMULTI || cTokenType == TokenTypeDIV {
+		return true
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package goculator
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Partial returns residual Expression where every variable which context can resolve is substituted
+// with its value and constant subexpressions are folded, as by Simplify(false).
+// Variables which context returns error for are kept in the residual Expression.
+// NaN and infinite values are not substituted because they cannot be written as number literals.
+func (e *Expression) Partial(context Context) (*Expression, error) {
+	result := &Expression{input: e.input, options: e.options}
+	if e.root == nil {
+		return result, nil
+	}
+
+	p := partialEvaluator{context: context}
+	root, err := p.substitute(e.root)
+	if err != nil {
+		return nil, err
+	}
+	result.root = root
+	return result.Simplify(false), nil
+}
+
+type partialEvaluator struct {
+	context Context
+}
+
+func (p *partialEvaluator) substitute(n node) (node, error) {
+	switch n := n.(type) {
+	case *numberNode:
+		return n, nil
+	case *variableNode:
+		return p.substituteVariable(n)
+	case *unaryNode:
+		operand, err := p.substitute(n.operand)
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op: n.op, operand: operand, s: n.s}, nil
+	case *binaryNode:
+		left, err := p.substitute(n.left)
+		if err != nil {
+			return nil, err
+		}
+		right, err := p.substitute(n.right)
+		if err != nil {
+			return nil, err
+		}
+		return &binaryNode{op: n.op, left: left, right: right, s: n.s, opPos: n.opPos}, nil
+	}
+	panic(fmt.Sprintf("unknown node %T", n))
+}
+
+func (p *partialEvaluator) substituteVariable(n *variableNode) (node, error) {
+	variable := &variableNode{path: make([]pathElementNode, len(n.path)), s: n.s}
+	for i, element := range n.path {
+		variable.path[i] = element
+		if element.index == nil {
+			continue
+		}
+
+		index, err := p.substitute(element.index)
+		if err != nil {
+			return nil, err
+		}
+		index = (&simplifier{}).simplify(index)
+		if value, ok := constantValue(index); ok {
+			if _, ok := toIndex(value); !ok {
+				return nil, errors.New(fmt.Sprintf("index %v of '%s' is not a non-negative integer", value, variableString(&variableNode{path: variable.path[:i]})))
+			}
+			index = newConstantNode(value, element.index.span())
+		}
+		variable.path[i].index = index
+	}
+
+	path, ok := variable.staticPath()
+	if !ok || p.context == nil {
+		return variable, nil
+	}
+
+	var result float64
+	var err error
+	if len(path) == 1 {
+		result, err = p.context.Value(path[0].Name)
+	} else {
+		result, err = pathValue(p.context, path)
+	}
+	if err != nil || math.IsNaN(result) || math.IsInf(result, 0) {
+		return variable, nil
+	}
+	return newConstantNode(result, n.s), nil
+}
@@ -0,0 +1,62 @@
+package goculator
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+func TestPartial(t *testing.T) {
+	assert := assert.New(t)
+	var testdata = []struct {
+		input  string
+		result string
+	}{
+		{"price * qty * (1 + tax_rate)", "price * qty * 1.2"},
+		{"(base + 2) * rate - discount", "6 - discount"},
+		{"base * 2 + zero", "20 + zero"},
+		{"items[index + 1].price * qty", "items[1].price * qty"},
+		{"items[idx].price", "items[idx].price"},
+		{"infinity + base", "infinity + 10"},
+		{"-base", "-10"},
+		{"", ""},
+	}
+
+	context := NewDefaultContext(map[string]float64{
+		"tax_rate": 0.2,
+		"base":     10,
+		"rate":     0.5,
+		"index":    0,
+		"infinity": math.Inf(1),
+	})
+
+	for _, data := range testdata {
+		expr, err := Parse(data.input)
+		if !assert.NoError(err, data.input) {
+			continue
+		}
+
+		partial, err := expr.Partial(context)
+		if assert.NoError(err, data.input) {
+			assert.Equal(data.result, partial.String(), data.input)
+		}
+	}
+
+	expr, _ := Parse("items[rate].price")
+	_, err := expr.Partial(context)
+	assert.EqualError(err, "index 0.5 of 'items' is not a non-negative integer")
+}
+
+func TestPartialEval(t *testing.T) {
+	assert := assert.New(t)
+
+	expr, _ := Parse("(price - cost) * qty / (1 + tax_rate)")
+	partial, err := expr.Partial(NewDefaultContext(map[string]float64{"tax_rate": 0.25, "cost": 4}))
+	assert.NoError(err)
+	assert.Equal("(price - 4) * qty / 1.25", partial.String())
+
+	context := NewDefaultContext(map[string]float64{"price": 9, "qty": 2})
+	result, err := partial.Eval(context)
+	assert.NoError(err)
+	assert.Equal(float64(8), result)
+}
@@ -0,0 +1,91 @@
+package goculator
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Policy decides how division by zero, NaN and infinity are handled in evaluation.
+type Policy int
+
+const (
+	// PolicyIEEE follows IEEE 754, e.g. 1/0 is +Inf and 0/0 is NaN without error.
+	PolicyIEEE Policy = iota
+	// PolicyErrorOnDivisionByZero returns *ArithmeticError on division by zero.
+	PolicyErrorOnDivisionByZero
+	// PolicyErrorOnNonFinite returns *ArithmeticError whenever a variable value,
+	// an intermediate result or the final result is NaN or ±Inf.
+	PolicyErrorOnNonFinite
+)
+
+// WithPolicy sets Policy of arithmetic. Default is PolicyIEEE.
+func WithPolicy(policy Policy) Option {
+	return func(o *options) {
+		o.policy = policy
+	}
+}
+
+// ArithmeticError is returned by evaluation if Policy does not allow a result.
+type ArithmeticError struct {
+	// Op is the operator, e.g. "/". It is empty if the value of a variable is not allowed.
+	Op string
+	// Operands are the operands of Op.
+	Operands []float64
+	// Variable is the name of variable if the value of a variable is not allowed.
+	Variable string
+	// Result is the result of Op or the value of Variable.
+	Result float64
+	// Pos is Position of the operator or the variable.
+	Pos Position
+}
+
+func (e *ArithmeticError) Error() string {
+	if e.Op == "" {
+		return fmt.Sprintf("variable '%s' at position %s is %s", e.Variable, e.Pos, formatFloat(e.Result))
+	}
+
+	var expression string
+	if len(e.Operands) == 1 {
+		expression = e.Op + formatFloat(e.Operands[0])
+	} else {
+		expression = formatFloat(e.Operands[0]) + " " + e.Op + " " + formatFloat(e.Operands[1])
+	}
+
+	if e.Op == "/" && e.Operands[1] == 0 {
+		return fmt.Sprintf("division by zero: %s at position %s", expression, e.Pos)
+	}
+	return fmt.Sprintf("non-finite result: %s = %s at position %s", expression, formatFloat(e.Result), e.Pos)
+}
+
+// checkBinary returns error if the result of binary operation is not allowed by Policy.
+func (p Policy) checkBinary(op TokenType, left, right, result float64, pos Position) error {
+	switch {
+	case p == PolicyIEEE:
+		return nil
+	case op == TokenTypeDIV && right == 0:
+	case p == PolicyErrorOnNonFinite && !isFinite(result):
+	default:
+		return nil
+	}
+	return &ArithmeticError{Op: operatorStrings[op], Operands: []float64{left, right}, Result: result, Pos: pos}
+}
+
+// allowsVariable reports whether value of a variable is allowed by Policy.
+// Callers build the name of the variable for variableError only if it is not allowed.
+func (p Policy) allowsVariable(value float64) bool {
+	return p != PolicyErrorOnNonFinite || isFinite(value)
+}
+
+// variableError returns error of variable name whose value is not allowed by Policy.
+func variableError(name string, value float64, pos Position) error {
+	return &ArithmeticError{Variable: name, Result: value, Pos: pos}
+}
+
+func isFinite(value float64) bool {
+	return !math.IsNaN(value) && !math.IsInf(value, 0)
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
@@ -0,0 +1,78 @@
+package goculator
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+func TestPolicy(t *testing.T) {
+	assert := assert.New(t)
+
+	context := NewDefaultContext(map[string]float64{"x": 0, "big": math.MaxFloat64, "nan": math.NaN()})
+	tests := []struct {
+		input  string
+		policy Policy
+		result float64
+		err    string
+	}{
+		{"1 / x", PolicyIEEE, math.Inf(1), ""},
+		{"1 / x", PolicyErrorOnDivisionByZero, 0, "division by zero: 1 / 0 at position 2"},
+		{"1 / x", PolicyErrorOnNonFinite, 0, "division by zero: 1 / 0 at position 2"},
+		{"-1 / (x * 2)", PolicyErrorOnDivisionByZero, 0, "division by zero: -1 / 0 at position 3"},
+		{"big * 2", PolicyErrorOnDivisionByZero, math.Inf(1), ""},
+		{"big * 2", PolicyErrorOnNonFinite, 0, "non-finite result: 1.7976931348623157e+308 * 2 = +Inf at position 4"},
+		{"1 + nan", PolicyErrorOnDivisionByZero, 0, ""},
+		{"1 + nan", PolicyErrorOnNonFinite, 0, "variable 'nan' at position 4 is NaN"},
+		{"x / 2 + 1", PolicyErrorOnNonFinite, 1, ""},
+	}
+
+	for _, test := range tests {
+		expr, err := Parse(test.input, WithPolicy(test.policy))
+		if !assert.NoError(err, test.input) {
+			continue
+		}
+
+		results := make([]float64, 2)
+		errs := make([]error, 2)
+		results[0], errs[0] = expr.Eval(context)
+		results[1], errs[1] = expr.Compile().Run(context)
+		for i := range results {
+			if test.err != "" {
+				if assert.IsType(&ArithmeticError{}, errs[i], test.input) {
+					assert.EqualError(errs[i], test.err, test.input)
+				}
+				continue
+			}
+			assert.NoError(errs[i], test.input)
+			if math.IsNaN(results[i]) {
+				continue
+			}
+			assert.Equal(test.result, results[i], test.input)
+		}
+	}
+
+	_, err := New("2 / (1 - 1)", WithPolicy(PolicyErrorOnDivisionByZero)).Go()
+	if assert.IsType(&ArithmeticError{}, err) {
+		arithmeticError := err.(*ArithmeticError)
+		assert.Equal("/", arithmeticError.Op)
+		assert.Equal([]float64{2, 0}, arithmeticError.Operands)
+		assert.Equal(Position{Offset: 2, Rune: 2}, arithmeticError.Pos)
+	}
+}
+
+func TestPolicyAllocs(t *testing.T) {
+	assert := assert.New(t)
+
+	context := NewDefaultContext(benchmarkValues)
+	for _, policy := range []Policy{PolicyIEEE, PolicyErrorOnDivisionByZero, PolicyErrorOnNonFinite} {
+		expr, _ := Parse(benchmarkInput, WithPolicy(policy))
+		program := expr.Compile()
+		closure := expr.CompileClosure()
+		slots := make([]float64, len(closure.Slots()))
+
+		assert.Equal(float64(0), testing.AllocsPerRun(100, func() { expr.Eval(context) }), "Eval")
+		assert.Equal(float64(0), testing.AllocsPerRun(100, func() { program.Run(context) }), "Run")
+		assert.Equal(float64(0), testing.AllocsPerRun(100, func() { closure.Call(slots) }), "Call")
+	}
+}
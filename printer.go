@@ -0,0 +1,151 @@
+package goculator
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// precedence of nodes. Higher binds tighter.
+const (
+	precedenceAdditive = iota + 1
+	precedenceMultiplicative
+	precedenceUnary
+	precedencePrimary
+)
+
+var operatorStrings = map[TokenType]string{
+	TokenTypePLUS:  "+",
+	TokenTypeMINUS: "-",
+	TokenTypeMULTI: "*",
+	TokenTypeDIV:   "/",
+}
+
+// Spacing is the style of spaces around binary operators.
+type Spacing int
+
+const (
+	// SpacingSpaced puts spaces around every binary operator, e.g. "a + b * c".
+	SpacingSpaced Spacing = iota
+	// SpacingCompact puts no space, e.g. "a+b*c".
+	SpacingCompact
+	// SpacingPrecedence omits spaces around operators which bind tighter than the enclosing operator,
+	// e.g. "a + b*c" but "b * c".
+	SpacingPrecedence
+)
+
+// PrintOptions are options of Expression.Format.
+type PrintOptions struct {
+	Spacing Spacing
+	// NormalizeNumbers prints numbers as the shortest decimal of their values instead of the literals as written,
+	// e.g. "255" for "0xFF" and "1000" for "1_000".
+	NormalizeNumbers bool
+}
+
+// String returns Expression as text with the default PrintOptions.
+func (e *Expression) String() string {
+	return e.Format(PrintOptions{})
+}
+
+// Format returns Expression as text with only the necessary parentheses according to precedence and associativity.
+// Numbers are printed with the decimal mark of Locale of Expression, so the text is parsed back to the same Expression
+// with the same options.
+func (e *Expression) Format(options PrintOptions) string {
+	p := printer{options: options, decimal: e.options.locale.Decimal}
+	if e.root != nil {
+		p.print(e.root, false)
+	}
+	return p.buf.String()
+}
+
+type printer struct {
+	buf     bytes.Buffer
+	options PrintOptions
+	// decimal is the decimal mark of numbers. Zero means '.'.
+	decimal rune
+}
+
+func precedence(n node) int {
+	switch n := n.(type) {
+	case *binaryNode:
+		if n.op == TokenTypePLUS || n.op == TokenTypeMINUS {
+			return precedenceAdditive
+		}
+		return precedenceMultiplicative
+	case *unaryNode:
+		return precedenceUnary
+	}
+	return precedencePrimary
+}
+
+// print writes n. compact is true if n is an operand of an operator which binds looser than n.
+func (p *printer) print(n node, compact bool) {
+	switch n := n.(type) {
+	case *numberNode:
+		p.buf.WriteString(p.numberLiteral(n))
+	case *variableNode:
+		for i, element := range n.path {
+			switch {
+			case element.index != nil:
+				p.buf.WriteString("[")
+				p.print(element.index, false)
+				p.buf.WriteString("]")
+			case i > 0:
+				p.buf.WriteString("." + quoteVariable(element.name))
+			default:
+				p.buf.WriteString(quoteVariable(element.name))
+			}
+		}
+	case *binaryNode:
+		// Operators are left associative, so the right operand of the same precedence needs parentheses.
+		leftParen := precedence(n.left) < precedence(n)
+		rightParen := precedence(n.right) <= precedence(n)
+		p.printOperand(n.left, leftParen, p.isCompact(n, n.left, compact))
+		if p.options.Spacing == SpacingCompact || compact {
+			p.buf.WriteString(operatorStrings[n.op])
+		} else {
+			p.buf.WriteString(" " + operatorStrings[n.op] + " ")
+		}
+		p.printOperand(n.right, rightParen, p.isCompact(n, n.right, compact))
+	case *unaryNode:
+		p.buf.WriteString(operatorStrings[n.op])
+		p.printOperand(n.operand, precedence(n.operand) < precedenceUnary, false)
+	default:
+		panic(fmt.Sprintf("unknown node %T", n))
+	}
+}
+
+// isCompact reports whether operand of n is printed without spaces.
+func (p *printer) isCompact(n node, operand node, compact bool) bool {
+	return compact || (p.options.Spacing == SpacingPrecedence && precedence(operand) > precedence(n))
+}
+
+func (p *printer) printOperand(n node, parenthesize bool, compact bool) {
+	if parenthesize {
+		p.buf.WriteString("(")
+		p.print(n, false)
+		p.buf.WriteString(")")
+		return
+	}
+	p.print(n, compact)
+}
+
+// numberLiteral returns the literal of number as written without grouping separators,
+// or the shortest literal of its value, with the decimal mark of printer.
+func (p *printer) numberLiteral(n *numberNode) string {
+	literal := n.literal
+	if literal == "" || p.options.NormalizeNumbers {
+		literal = strconv.FormatFloat(n.value, 'g', -1, 64)
+	}
+	// The literal of a number token always has '.' as the decimal mark.
+	return replaceDecimal(literal, p.decimal)
+}
+
+// replaceDecimal returns number text with '.' replaced by decimal. Zero decimal means '.'.
+func replaceDecimal(text string, decimal rune) string {
+	if decimal == 0 || decimal == '.' {
+		return text
+	}
+	return strings.Replace(text, ".", string(decimal), 1)
+}
@@ -0,0 +1,107 @@
+package goculator
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	assert := assert.New(t)
+	var testdata = []struct {
+		input   string
+		options PrintOptions
+		result  string
+	}{
+		{"((a+b))*(c)", PrintOptions{}, "(a + b) * c"},
+		{"a-(b-c)-(d+e)", PrintOptions{}, "a - (b - c) - (d + e)"},
+		{"a/(b*c)*(d/e)", PrintOptions{}, "a / (b * c) * (d / e)"},
+		{"-(a+b) - (-c)", PrintOptions{}, "-(a + b) - -c"},
+		{"a + b*c*d - e/f", PrintOptions{Spacing: SpacingPrecedence}, "a + b*c*d - e/f"},
+		{"(a + b) * (c - d)", PrintOptions{Spacing: SpacingPrecedence}, "(a + b) * (c - d)"},
+		{"a * -(b + c)", PrintOptions{Spacing: SpacingPrecedence}, "a * -(b + c)"},
+		{"x[i + 1*2].y + 3", PrintOptions{Spacing: SpacingPrecedence}, "x[i + 1*2].y + 3"},
+		{"a + b * (c - d)", PrintOptions{Spacing: SpacingCompact}, "a+b*(c-d)"},
+		{"a - -b", PrintOptions{Spacing: SpacingCompact}, "a--b"},
+		{"0xFF + 1_000 * 1.50e1", PrintOptions{}, "0xFF + 1_000 * 1.50e1"},
+		{"0xFF + 1_000 * 1.50e1", PrintOptions{NormalizeNumbers: true}, "255 + 1000 * 15"},
+		{"1.234,5", PrintOptions{NormalizeNumbers: true}, "1234,5"},
+		{"1.234,5 * x", PrintOptions{}, "1234,5 * x"},
+		{"`unit price` * a.`b c`[2]", PrintOptions{}, "`unit price` * a.`b c`[2]"},
+		{"/* c */ a # d", PrintOptions{}, "a"},
+	}
+
+	for _, data := range testdata {
+		var opts []Option
+		if strings.HasPrefix(data.input, "1.234,5") {
+			opts = append(opts, WithLocale(DecimalCommaLocale))
+		}
+		expr, err := Parse(data.input, opts...)
+		if assert.NoError(err, data.input) {
+			assert.Equal(data.result, expr.Format(data.options), data.input)
+		}
+	}
+}
+
+// TestFormatRoundTrip checks that formatted random expressions are parsed back to identical AST.
+func TestFormatRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+	random := rand.New(rand.NewSource(1))
+	options := []PrintOptions{
+		PrintOptions{},
+		PrintOptions{Spacing: SpacingCompact},
+		PrintOptions{Spacing: SpacingPrecedence, NormalizeNumbers: true},
+	}
+
+	for i := 0; i < 1000; i++ {
+		root := randomNode(random, 5)
+		for _, locale := range []Locale{DefaultLocale, DecimalCommaLocale} {
+			expr := &Expression{root: root, options: newOptions([]Option{WithLocale(locale)})}
+			for _, option := range options {
+				text := expr.Format(option)
+				parsed, err := Parse(text, WithLocale(locale))
+				if assert.NoError(err, text) {
+					assert.True(equalNodes(expr.root, parsed.root), text)
+				}
+			}
+		}
+	}
+}
+
+func randomNode(random *rand.Rand, depth int) node {
+	kind := random.Intn(6)
+	if depth == 0 {
+		kind %= 2
+	}
+
+	switch kind {
+	case 0:
+		value := float64(random.Intn(1000)) / float64(1+random.Intn(8))
+		literals := []string{
+			strconv.FormatFloat(value, 'g', -1, 64),
+			strconv.FormatFloat(value, 'e', -1, 64),
+		}
+		if value == float64(int(value)) {
+			literals = append(literals, "0x"+strconv.FormatInt(int64(value), 16))
+		}
+		return &numberNode{value: value, literal: literals[random.Intn(len(literals))]}
+	case 1:
+		names := []string{"x", "y_1", "größe", "unit price", "a`b", "net-revenue"}
+		variable := &variableNode{path: []pathElementNode{pathElementNode{name: names[random.Intn(len(names))]}}}
+		for random.Intn(3) == 0 && depth > 0 {
+			if random.Intn(2) == 0 {
+				variable.path = append(variable.path, pathElementNode{name: names[random.Intn(len(names))]})
+			} else {
+				variable.path = append(variable.path, pathElementNode{index: randomNode(random, depth-1)})
+			}
+		}
+		return variable
+	case 2:
+		ops := []TokenType{TokenTypePLUS, TokenTypeMINUS}
+		return &unaryNode{op: ops[random.Intn(len(ops))], operand: randomNode(random, depth-1)}
+	}
+	ops := []TokenType{TokenTypePLUS, TokenTypeMINUS, TokenTypeMULTI, TokenTypeDIV}
+	return &binaryNode{op: ops[random.Intn(len(ops))], left: randomNode(random, depth-1), right: randomNode(random, depth-1)}
+}
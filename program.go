@@ -0,0 +1,275 @@
+package goculator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+type opcode uint8
+
+const (
+	// opConst pushes constants[arg].
+	opConst opcode = iota
+	// opVar pushes the value of names[arg].
+	opVar
+	// opPath pops the dynamic indexes of paths[arg] and pushes the value of the path.
+	opPath
+	opAdd
+	opSub
+	opMul
+	opDiv
+	opNeg
+)
+
+var binaryOpcodes = map[TokenType]opcode{
+	TokenTypePLUS:  opAdd,
+	TokenTypeMINUS: opSub,
+	TokenTypeMULTI: opMul,
+	TokenTypeDIV:   opDiv,
+}
+
+var opcodeTokenTypes = [...]TokenType{
+	opAdd: TokenTypePLUS,
+	opSub: TokenTypeMINUS,
+	opMul: TokenTypeMULTI,
+	opDiv: TokenTypeDIV,
+}
+
+type instruction struct {
+	op  opcode
+	arg uint32
+}
+
+// pathRef is variable path referred by opPath.
+type pathRef struct {
+	// path has zero indexes at dynamic positions which are filled at run time.
+	path Path
+	// key is the string of path. It is empty if path has dynamic indexes.
+	key string
+	// dynamic is the positions of index elements evaluated at run time in the order they are pushed.
+	dynamic []int
+	// name is the variable as written in Expression, which is used in errors.
+	name string
+}
+
+// Program is bytecode compiled from Expression, which is evaluated by a stack machine.
+// Program is immutable and evaluation does not allocate memory,
+// except for variables whose index is not a number literal.
+type Program struct {
+	code      []instruction
+	constants []float64
+	names     []string
+	paths     []pathRef
+	stackSize int
+	// steps is the number of nodes, each of which is evaluated once, like Closure.
+	// It can be more than len(code), because unary plus and number literal indexes have no instruction.
+	steps    int
+	maxSteps int
+	policy   Policy
+	// positions are Positions of operators and variables for each instruction.
+	positions []Position
+}
+
+// stackBufferSize is the stack size of Program which can be allocated on the goroutine stack.
+const stackBufferSize = 32
+
+// Compile compiles Expression to Program.
+func (e *Expression) Compile() *Program {
+	c := &compiler{program: &Program{maxSteps: e.options.limits.MaxSteps, policy: e.options.policy}, nameIndex: make(map[string]int)}
+	if e.root != nil {
+		c.compile(e.root)
+	}
+	return c.program
+}
+
+type compiler struct {
+	program   *Program
+	nameIndex map[string]int
+	depth     int
+}
+
+func (c *compiler) compile(n node) {
+	c.program.steps++
+	switch n := n.(type) {
+	case *numberNode:
+		c.program.constants = append(c.program.constants, n.value)
+		c.emit(opConst, len(c.program.constants)-1, 1, n.s.Start)
+	case *variableNode:
+		if len(n.path) == 1 {
+			index, ok := c.nameIndex[n.path[0].name]
+			if !ok {
+				c.program.names = append(c.program.names, n.path[0].name)
+				index = len(c.program.names) - 1
+				c.nameIndex[n.path[0].name] = index
+			}
+			c.emit(opVar, index, 1, n.s.Start)
+			return
+		}
+
+		ref := pathRef{path: make(Path, len(n.path)), name: variableKey(n)}
+		for i, element := range n.path {
+			if element.index == nil {
+				ref.path[i] = PathElement{Name: element.name}
+				continue
+			}
+			ref.path[i] = PathElement{IsIndex: true}
+			if number, ok := element.index.(*numberNode); ok {
+				if index, ok := toIndex(number.value); ok {
+					ref.path[i].Index = index
+					c.program.steps++
+					continue
+				}
+			}
+			c.compile(element.index)
+			ref.dynamic = append(ref.dynamic, i)
+		}
+		if len(ref.dynamic) == 0 {
+			ref.key = ref.path.String()
+		}
+		c.program.paths = append(c.program.paths, ref)
+		c.emit(opPath, len(c.program.paths)-1, 1-len(ref.dynamic), n.s.Start)
+	case *binaryNode:
+		c.compile(n.left)
+		c.compile(n.right)
+		c.emit(binaryOpcodes[n.op], 0, -1, n.opPos)
+	case *unaryNode:
+		c.compile(n.operand)
+		if n.op == TokenTypeMINUS {
+			c.emit(opNeg, 0, 0, n.s.Start)
+		}
+	default:
+		panic(fmt.Sprintf("unknown node %T", n))
+	}
+}
+
+// emit appends instruction at pos which changes the stack depth by delta.
+func (c *compiler) emit(op opcode, arg int, delta int, pos Position) {
+	c.program.code = append(c.program.code, instruction{op: op, arg: uint32(arg)})
+	c.program.positions = append(c.program.positions, pos)
+	c.depth += delta
+	if c.program.stackSize < c.depth {
+		c.program.stackSize = c.depth
+	}
+}
+
+// Run evaluates Program with variables in context and returns result and error.
+// context can be nil if Program has no variable.
+func (p *Program) Run(context Context) (float64, error) {
+	return p.run(nil, context)
+}
+
+// run evaluates Program and checks ctx for cancellation if ctx is not nil.
+func (p *Program) run(ctx context.Context, vars Context) (float64, error) {
+	if p.maxSteps > 0 && p.steps > p.maxSteps {
+		return 0, &StepLimitError{Max: p.maxSteps}
+	}
+
+	var buffer [stackBufferSize]float64
+	stack := buffer[:]
+	if p.stackSize > stackBufferSize {
+		stack = make([]float64, p.stackSize)
+	}
+
+	sp := 0
+	for i, in := range p.code {
+		if ctx != nil && i%cancelCheckInterval == cancelCheckInterval-1 {
+			if err := ctx.Err(); err != nil {
+				return 0, err
+			}
+		}
+
+		switch in.op {
+		case opConst:
+			stack[sp] = p.constants[in.arg]
+			sp++
+		case opVar:
+			result, err := valueContext(ctx, vars, p.names[in.arg])
+			if err != nil {
+				return 0, err
+			}
+			if !p.policy.allowsVariable(result) {
+				return 0, variableError(p.names[in.arg], result, p.positions[i])
+			}
+			stack[sp] = result
+			sp++
+		case opPath:
+			ref := &p.paths[in.arg]
+			sp -= len(ref.dynamic)
+			result, err := p.pathValue(ctx, vars, ref, stack[sp:sp+len(ref.dynamic)])
+			if err != nil {
+				return 0, err
+			}
+			if !p.policy.allowsVariable(result) {
+				return 0, variableError(ref.name, result, p.positions[i])
+			}
+			stack[sp] = result
+			sp++
+		case opAdd:
+			sp--
+			if p.policy != PolicyIEEE {
+				if err := p.checkBinary(i, stack[sp-1], stack[sp]); err != nil {
+					return 0, err
+				}
+			}
+			stack[sp-1] += stack[sp]
+		case opSub:
+			sp--
+			if p.policy != PolicyIEEE {
+				if err := p.checkBinary(i, stack[sp-1], stack[sp]); err != nil {
+					return 0, err
+				}
+			}
+			stack[sp-1] -= stack[sp]
+		case opMul:
+			sp--
+			if p.policy != PolicyIEEE {
+				if err := p.checkBinary(i, stack[sp-1], stack[sp]); err != nil {
+					return 0, err
+				}
+			}
+			stack[sp-1] *= stack[sp]
+		case opDiv:
+			sp--
+			if p.policy != PolicyIEEE {
+				if err := p.checkBinary(i, stack[sp-1], stack[sp]); err != nil {
+					return 0, err
+				}
+			}
+			stack[sp-1] /= stack[sp]
+		case opNeg:
+			stack[sp-1] = -stack[sp-1]
+		}
+	}
+
+	if sp == 0 {
+		return 0, nil
+	}
+	return stack[0], nil
+}
+
+// checkBinary checks the binary operation of code[i] with Policy.
+func (p *Program) checkBinary(i int, left, right float64) error {
+	op := opcodeTokenTypes[p.code[i].op]
+	return p.policy.checkBinary(op, left, right, binary(op, left, right), p.positions[i])
+}
+
+func (p *Program) pathValue(ctx context.Context, vars Context, ref *pathRef, indexes []float64) (float64, error) {
+	if len(ref.dynamic) == 0 {
+		if _, ok := vars.(PathContext); ok {
+			return pathValueContext(ctx, vars, ref.path)
+		}
+		return valueContext(ctx, vars, ref.key)
+	}
+
+	path := make(Path, len(ref.path))
+	copy(path, ref.path)
+	for i, position := range ref.dynamic {
+		index, ok := toIndex(indexes[i])
+		if !ok {
+			return 0, errors.New(fmt.Sprintf("index %v of '%s' is not a non-negative integer", indexes[i], path[:position]))
+		}
+		path[position].Index = index
+	}
+	return pathValueContext(ctx, vars, path)
+}
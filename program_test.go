@@ -0,0 +1,133 @@
+package goculator
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+func TestProgram(t *testing.T) {
+	assert := assert.New(t)
+	var testdata = []string{
+		"",
+		"32+21.1-21",
+		"2.1-2*4/2+1",
+		"2.1/(var1 + var2)",
+		"var1 * (var2 - (var1 / (var2 + var1 * 2)))",
+		"items[1].price * items[idx].qty + items[0].price",
+		"items[items[0].qty].price",
+		"2.1/0",
+		"0/0",
+	}
+
+	context := NewNestedContext(
+		map[string]interface{}{
+			"var1": 2.1,
+			"var2": 4.2,
+			"idx":  1,
+			"items": []map[string]float64{
+				{"price": 1, "qty": 1},
+				{"price": 2, "qty": 3},
+			},
+		},
+	)
+
+	for _, input := range testdata {
+		expr, err := Parse(input)
+		if !assert.NoError(err, input) {
+			continue
+		}
+
+		expected, err := expr.Eval(context)
+		assert.NoError(err, input)
+
+		result, err := expr.Compile().Run(context)
+		assert.NoError(err, input)
+
+		if math.IsNaN(expected) {
+			assert.True(math.IsNaN(result), input)
+		} else {
+			assert.Equal(expected, result, input)
+		}
+	}
+}
+
+func TestProgramError(t *testing.T) {
+	assert := assert.New(t)
+
+	expr, _ := Parse("items[idx].price")
+	_, err := expr.Compile().Run(NewNestedContext(map[string]interface{}{"idx": 0.5}))
+	assert.EqualError(err, "index 0.5 of 'items' is not a non-negative integer")
+
+	_, err = expr.Compile().Run(nil)
+	assert.EqualError(err, "no context given for variable")
+}
+
+func TestProgramDeepStack(t *testing.T) {
+	assert := assert.New(t)
+
+	input := "1"
+	for i := 0; i < stackBufferSize*2; i++ {
+		input = "1+(" + input + ")"
+	}
+	expr, err := Parse(input)
+	assert.NoError(err)
+
+	result, err := expr.Compile().Run(nil)
+	assert.NoError(err)
+	assert.Equal(float64(stackBufferSize*2+1), result)
+}
+
+func TestProgramAllocs(t *testing.T) {
+	assert := assert.New(t)
+
+	expr, _ := Parse(benchmarkInput)
+	program := expr.Compile()
+	context := NewDefaultContext(benchmarkValues)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		program.Run(context)
+	})
+	assert.Equal(float64(0), allocs)
+}
+
+const benchmarkInput = "(price - cost) * qty / (1 + tax_rate) - discount * qty"
+
+var benchmarkValues = map[string]float64{
+	"price":    32,
+	"cost":     21.5,
+	"qty":      3,
+	"tax_rate": 0.1,
+	"discount": 1.25,
+}
+
+func BenchmarkCalculatorGo(b *testing.B) {
+	context := NewDefaultContext(benchmarkValues)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		calc := New(benchmarkInput)
+		calc.Bind(context)
+		calc.Go()
+	}
+}
+
+func BenchmarkExpressionEval(b *testing.B) {
+	context := NewDefaultContext(benchmarkValues)
+	expr, _ := Parse(benchmarkInput)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		expr.Eval(context)
+	}
+}
+
+func BenchmarkProgramRun(b *testing.B) {
+	context := NewDefaultContext(benchmarkValues)
+	expr, _ := Parse(benchmarkInput)
+	program := expr.Compile()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		program.Run(context)
+	}
+}
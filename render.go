@@ -0,0 +1,290 @@
+package goculator
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// RenderOptions are options of Expression.LaTeX and Expression.MathML.
+type RenderOptions struct {
+	// Variables overrides how variables are displayed. Key is the variable as printed by Expression.String,
+	// such as "x" or "items[2].price", and value is LaTeX or MathML markup which is written as it is.
+	Variables map[string]string
+}
+
+var greekLetters = []string{
+	"alpha", "beta", "gamma", "delta", "epsilon", "zeta", "eta", "theta", "iota", "kappa", "lambda", "mu",
+	"nu", "xi", "omicron", "pi", "rho", "sigma", "tau", "upsilon", "phi", "chi", "psi", "omega",
+}
+
+// greekLaTeX is LaTeX command of Greek letter names and Greek letters, e.g. "alpha" → "\alpha", "π" → "\pi".
+var greekLaTeX = map[string]string{}
+
+// greekRunes is Greek letter of Greek letter names, e.g. "alpha" → "α", "Omega" → "Ω".
+var greekRunes = map[string]string{}
+
+func init() {
+	// LaTeX has no command for capital Greek letters which look like Latin letters.
+	capitals := map[string]bool{
+		"gamma": true, "delta": true, "theta": true, "lambda": true, "xi": true, "pi": true,
+		"sigma": true, "upsilon": true, "phi": true, "psi": true, "omega": true,
+	}
+	for i, name := range greekLetters {
+		small := string(rune('α' + i))
+		capital := string(rune('Α' + i))
+		if i >= 17 {
+			// skip U+03A2 which is not assigned for capital final sigma
+			capital = string(rune('Α' + i + 1))
+			small = string(rune('α' + i + 1))
+		}
+		title := strings.ToUpper(name[:1]) + name[1:]
+
+		greekRunes[name] = small
+		greekRunes[title] = capital
+		if name == "omicron" {
+			greekLaTeX[name] = "o"
+			greekLaTeX[small] = "o"
+		} else {
+			greekLaTeX[name] = `\` + name
+			greekLaTeX[small] = `\` + name
+		}
+		if capitals[name] {
+			greekLaTeX[title] = `\` + title
+			greekLaTeX[capital] = `\` + title
+		}
+	}
+}
+
+// LaTeX returns Expression as LaTeX math. Division is rendered with \frac and
+// Greek letter names like alpha with Greek letters.
+func (e *Expression) LaTeX(options RenderOptions) string {
+	r := latexRenderer{options: options}
+	if e.root != nil {
+		r.render(e.root)
+	}
+	return r.buf.String()
+}
+
+type latexRenderer struct {
+	buf     bytes.Buffer
+	options RenderOptions
+}
+
+func (r *latexRenderer) render(n node) {
+	switch n := n.(type) {
+	case *numberNode:
+		mantissa, exponent := splitExponent(n.value)
+		r.buf.WriteString(mantissa)
+		if exponent != "" {
+			r.buf.WriteString(` \times 10^{` + exponent + `}`)
+		}
+	case *variableNode:
+		if markup, ok := r.options.Variables[variableString(n)]; ok {
+			r.buf.WriteString(markup)
+			return
+		}
+		for i, element := range n.path {
+			switch {
+			case element.index != nil:
+				r.buf.WriteString(`\left[`)
+				r.render(element.index)
+				r.buf.WriteString(`\right]`)
+			case i > 0:
+				r.buf.WriteString(".")
+				r.buf.WriteString(latexName(element.name))
+			default:
+				r.buf.WriteString(latexName(element.name))
+			}
+		}
+	case *binaryNode:
+		if n.op == TokenTypeDIV {
+			r.buf.WriteString(`\frac{`)
+			r.render(n.left)
+			r.buf.WriteString(`}{`)
+			r.render(n.right)
+			r.buf.WriteString(`}`)
+			return
+		}
+		r.renderOperand(n.left, renderPrecedence(n.left) < renderPrecedence(n))
+		switch n.op {
+		case TokenTypeMULTI:
+			r.buf.WriteString(` \cdot `)
+		default:
+			r.buf.WriteString(" " + operatorStrings[n.op] + " ")
+		}
+		r.renderOperand(n.right, renderPrecedence(n.right) <= renderPrecedence(n))
+	case *unaryNode:
+		r.buf.WriteString(operatorStrings[n.op])
+		r.renderOperand(n.operand, renderPrecedence(n.operand) < precedenceUnary)
+	default:
+		panic(fmt.Sprintf("unknown node %T", n))
+	}
+}
+
+func (r *latexRenderer) renderOperand(n node, parenthesize bool) {
+	if parenthesize {
+		r.buf.WriteString(`\left(`)
+	}
+	r.render(n)
+	if parenthesize {
+		r.buf.WriteString(`\right)`)
+	}
+}
+
+// latexName returns LaTeX of variable name. A name like x_1 is rendered with subscript.
+func latexName(name string) string {
+	if command, ok := greekLaTeX[name]; ok {
+		return command
+	}
+	if i := strings.Index(name, "_"); i > 0 && i < len(name)-1 {
+		return latexName(name[:i]) + "_{" + latexName(name[i+1:]) + "}"
+	}
+	if utf8.RuneCountInString(name) == 1 {
+		return latexEscape(name)
+	}
+	if _, err := strconv.Atoi(name); err == nil {
+		return name
+	}
+	return `\mathrm{` + latexEscape(name) + `}`
+}
+
+var latexEscaper = strings.NewReplacer(
+	`\`, `\textbackslash{}`,
+	`{`, `\{`,
+	`}`, `\}`,
+	`_`, `\_`,
+	`^`, `\^{}`,
+	`#`, `\#`,
+	`$`, `\$`,
+	`%`, `\%`,
+	`&`, `\&`,
+	`~`, `\~{}`,
+	` `, `\ `,
+)
+
+func latexEscape(text string) string {
+	return latexEscaper.Replace(text)
+}
+
+// MathML returns Expression as presentation MathML. Division is rendered with mfrac and
+// Greek letter names like alpha with Greek letters.
+func (e *Expression) MathML(options RenderOptions) string {
+	r := mathMLRenderer{options: options}
+	r.buf.WriteString(`<math xmlns="http://www.w3.org/1998/Math/MathML"><mrow>`)
+	if e.root != nil {
+		r.render(e.root)
+	}
+	r.buf.WriteString(`</mrow></math>`)
+	return r.buf.String()
+}
+
+type mathMLRenderer struct {
+	buf     bytes.Buffer
+	options RenderOptions
+}
+
+var mathMLOperators = map[TokenType]string{
+	TokenTypePLUS:  "+",
+	TokenTypeMINUS: "&#x2212;",
+	TokenTypeMULTI: "&#x22C5;",
+}
+
+func (r *mathMLRenderer) render(n node) {
+	switch n := n.(type) {
+	case *numberNode:
+		mantissa, exponent := splitExponent(n.value)
+		r.buf.WriteString("<mn>" + mantissa + "</mn>")
+		if exponent != "" {
+			r.buf.WriteString("<mo>&#xD7;</mo><msup><mn>10</mn><mn>" + exponent + "</mn></msup>")
+		}
+	case *variableNode:
+		if markup, ok := r.options.Variables[variableString(n)]; ok {
+			r.buf.WriteString(markup)
+			return
+		}
+		for i, element := range n.path {
+			switch {
+			case element.index != nil:
+				r.buf.WriteString("<mo>[</mo><mrow>")
+				r.render(element.index)
+				r.buf.WriteString("</mrow><mo>]</mo>")
+			case i > 0:
+				r.buf.WriteString("<mo>.</mo>" + mathMLName(element.name))
+			default:
+				r.buf.WriteString(mathMLName(element.name))
+			}
+		}
+	case *binaryNode:
+		if n.op == TokenTypeDIV {
+			r.buf.WriteString("<mfrac><mrow>")
+			r.render(n.left)
+			r.buf.WriteString("</mrow><mrow>")
+			r.render(n.right)
+			r.buf.WriteString("</mrow></mfrac>")
+			return
+		}
+		r.renderOperand(n.left, renderPrecedence(n.left) < renderPrecedence(n))
+		r.buf.WriteString("<mo>" + mathMLOperators[n.op] + "</mo>")
+		r.renderOperand(n.right, renderPrecedence(n.right) <= renderPrecedence(n))
+	case *unaryNode:
+		r.buf.WriteString("<mo>" + mathMLOperators[n.op] + "</mo>")
+		r.renderOperand(n.operand, renderPrecedence(n.operand) < precedenceUnary)
+	default:
+		panic(fmt.Sprintf("unknown node %T", n))
+	}
+}
+
+func (r *mathMLRenderer) renderOperand(n node, parenthesize bool) {
+	if parenthesize {
+		r.buf.WriteString("<mrow><mo>(</mo>")
+	}
+	r.render(n)
+	if parenthesize {
+		r.buf.WriteString("<mo>)</mo></mrow>")
+	}
+}
+
+// mathMLName returns MathML of variable name. A name like x_1 is rendered with subscript.
+func mathMLName(name string) string {
+	if letter, ok := greekRunes[name]; ok {
+		return "<mi>" + letter + "</mi>"
+	}
+	if i := strings.Index(name, "_"); i > 0 && i < len(name)-1 {
+		return "<msub>" + mathMLName(name[:i]) + mathMLName(name[i+1:]) + "</msub>"
+	}
+	if _, err := strconv.Atoi(name); err == nil {
+		return "<mn>" + name + "</mn>"
+	}
+	return "<mi>" + html.EscapeString(name) + "</mi>"
+}
+
+// renderPrecedence is precedence of node when division is rendered as a fraction.
+func renderPrecedence(n node) int {
+	if binary, ok := n.(*binaryNode); ok && binary.op == TokenTypeDIV {
+		return precedencePrimary
+	}
+	return precedence(n)
+}
+
+// splitExponent returns the shortest decimal of value split into mantissa and exponent.
+// exponent is empty if the decimal has no exponent.
+func splitExponent(value float64) (string, string) {
+	s := strconv.FormatFloat(value, 'g', -1, 64)
+	i := strings.IndexByte(s, 'e')
+	if i < 0 {
+		return s, ""
+	}
+	exponent, _ := strconv.Atoi(s[i+1:])
+	return s[:i], strconv.Itoa(exponent)
+}
+
+// variableString returns variable as printed by Expression.String.
+func variableString(n *variableNode) string {
+	p := printer{}
+	p.print(n, false)
+	return p.buf.String()
+}
@@ -0,0 +1,70 @@
+package goculator
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestLaTeX(t *testing.T) {
+	assert := assert.New(t)
+	var testdata = []struct {
+		input   string
+		options RenderOptions
+		result  string
+	}{
+		{"(a + b) / (c - 1) * 2", RenderOptions{}, `\frac{a + b}{c - 1} \cdot 2`},
+		{"alpha * (beta - Gamma) - -π", RenderOptions{}, `\alpha \cdot \left(\beta - \Gamma\right) - -\pi`},
+		{"x_1 + alpha_max / 1e-6", RenderOptions{}, `x_{1} + \frac{\alpha_{\mathrm{max}}}{1 \times 10^{-6}}`},
+		{"`unit price` * items[i + 1].price", RenderOptions{}, `\mathrm{unit\ price} \cdot \mathrm{items}\left[i + 1\right].\mathrm{price}`},
+		{"-(a - b) * 100", RenderOptions{}, `-\left(a - b\right) \cdot 100`},
+		{
+			"price * qty",
+			RenderOptions{Variables: map[string]string{"price": `P_{\text{unit}}`}},
+			`P_{\text{unit}} \cdot \mathrm{qty}`,
+		},
+	}
+
+	for _, data := range testdata {
+		expr, err := Parse(data.input)
+		if assert.NoError(err, data.input) {
+			assert.Equal(data.result, expr.LaTeX(data.options), data.input)
+		}
+	}
+}
+
+func TestMathML(t *testing.T) {
+	assert := assert.New(t)
+	var testdata = []struct {
+		input   string
+		options RenderOptions
+		result  string
+	}{
+		{
+			"(a + b) / 2",
+			RenderOptions{},
+			`<mfrac><mrow><mi>a</mi><mo>+</mo><mi>b</mi></mrow><mrow><mn>2</mn></mrow></mfrac>`,
+		},
+		{
+			"-alpha * (x_1 - 2.5e10)",
+			RenderOptions{},
+			`<mo>&#x2212;</mo><mi>α</mi><mo>&#x22C5;</mo><mrow><mo>(</mo><msub><mi>x</mi><mn>1</mn></msub><mo>&#x2212;</mo>` +
+				`<mn>2.5</mn><mo>&#xD7;</mo><msup><mn>10</mn><mn>10</mn></msup><mo>)</mo></mrow>`,
+		},
+		{
+			"`a<b` * Omega",
+			RenderOptions{Variables: map[string]string{"Omega": "<mi>Ω₀</mi>"}},
+			`<mi>a&lt;b</mi><mo>&#x22C5;</mo><mi>Ω₀</mi>`,
+		},
+	}
+
+	for _, data := range testdata {
+		expr, err := Parse(data.input)
+		if assert.NoError(err, data.input) {
+			assert.Equal(
+				`<math xmlns="http://www.w3.org/1998/Math/MathML"><mrow>`+data.result+`</mrow></math>`,
+				expr.MathML(data.options),
+				data.input,
+			)
+		}
+	}
+}
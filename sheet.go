@@ -0,0 +1,264 @@
+package goculator
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+)
+
+// Change is a change of the value of a formula in Sheet, including a change from a value to an error or back.
+type Change struct {
+	Name string
+	Old  float64
+	New  float64
+	// OldErr and NewErr are the errors of the formula before and after the change. Old or New is zero
+	// if its error is not nil.
+	OldErr error
+	NewErr error
+}
+
+// Sheet evaluates formulas of FormulaSet over mutable input values and recomputes incrementally.
+// Setting an input marks only the formulas which depend on it as dirty.
+// Dirty formulas are recomputed when their values are requested, by Recompute,
+// or immediately when Sheet is eager. Subscribers are notified of values which actually changed.
+// Sheet is safe for concurrent use. Subscribers are called without holding the lock of Sheet.
+type Sheet struct {
+	mu sync.Mutex
+	// formulas and dependencies are copied from FormulaSet, so that Sheet does not read FormulaSet
+	// which may be modified concurrently.
+	formulas     map[string]*Expression
+	dependencies map[string][]string
+	order        []string
+	dependents   map[string][]string
+	inputs       map[string]float64
+	values       map[string]float64
+	errs         map[string]error
+	dirty        map[string]bool
+	eager        bool
+	subscribers  map[int]func(Change)
+	nextID       int
+}
+
+// NewSheet returns new Sheet of formulas in set with initial inputs and evaluates all formulas.
+// If eager is true, formulas are recomputed as soon as an input changes.
+// Formulas added to set after NewSheet are not evaluated by Sheet.
+func NewSheet(set *FormulaSet, inputs map[string]float64, eager bool) (*Sheet, error) {
+	order, err := set.Order()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Sheet{
+		formulas:     make(map[string]*Expression, len(order)),
+		dependencies: make(map[string][]string, len(order)),
+		order:        order,
+		dependents:   make(map[string][]string),
+		inputs:       make(map[string]float64, len(inputs)),
+		values:       make(map[string]float64, len(order)),
+		errs:         make(map[string]error),
+		dirty:        make(map[string]bool, len(order)),
+		eager:        eager,
+		subscribers:  make(map[int]func(Change)),
+	}
+	for name, value := range inputs {
+		s.inputs[name] = value
+	}
+	for _, name := range order {
+		expr, _ := set.Expression(name)
+		s.formulas[name] = expr
+		s.dependencies[name] = set.Dependencies(name)
+		for _, variable := range expr.VariableNames() {
+			s.dependents[variable] = append(s.dependents[variable], name)
+		}
+		s.dirty[name] = true
+	}
+
+	s.recompute(nil)
+	return s, nil
+}
+
+// Subscribe registers f which is called for every formula whose value changed on recomputation.
+// It returns the function which unregisters f.
+func (s *Sheet) Subscribe(f func(Change)) func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := s.nextID
+	s.nextID++
+	s.subscribers[id] = f
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.subscribers, id)
+	}
+}
+
+// Set sets input value of name and marks formulas which depend on it as dirty.
+// name should not be a name of formula.
+func (s *Sheet) Set(name string, value float64) error {
+	s.mu.Lock()
+	if _, ok := s.formulas[name]; ok {
+		s.mu.Unlock()
+		return errors.New(fmt.Sprintf("'%s' is a formula, not an input", name))
+	}
+	if old, ok := s.inputs[name]; ok && sameValue(old, value) {
+		s.mu.Unlock()
+		return nil
+	}
+	s.inputs[name] = value
+	s.markDirty(name)
+
+	var changes []Change
+	if s.eager {
+		changes = s.recompute(nil)
+	}
+	s.mu.Unlock()
+
+	s.notify(changes)
+	return nil
+}
+
+// Value returns the value of formula or input of key. A dirty formula is recomputed with its dirty dependencies.
+// Sheet is Context, so formulas of other expressions can refer to formulas and inputs of Sheet.
+func (s *Sheet) Value(key string) (float64, error) {
+	s.mu.Lock()
+	var changes []Change
+	if s.dirty[key] {
+		changes = s.recompute(s.dependencyClosure(key))
+	}
+	value, err := s.value(key)
+	s.mu.Unlock()
+
+	s.notify(changes)
+	return value, err
+}
+
+// Recompute recomputes all dirty formulas.
+func (s *Sheet) Recompute() {
+	s.mu.Lock()
+	changes := s.recompute(nil)
+	s.mu.Unlock()
+
+	s.notify(changes)
+}
+
+// Dirty returns names of formulas which are not recomputed since their inputs changed, in topological order.
+func (s *Sheet) Dirty() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dirty := make([]string, 0)
+	for _, name := range s.order {
+		if s.dirty[name] {
+			dirty = append(dirty, name)
+		}
+	}
+	return dirty
+}
+
+func (s *Sheet) value(key string) (float64, error) {
+	if _, ok := s.values[key]; ok || s.errs[key] != nil {
+		return s.values[key], s.errs[key]
+	}
+	if value, ok := s.inputs[key]; ok {
+		return value, nil
+	}
+	return 0, errors.New(fmt.Sprintf("no value for key '%s'", key))
+}
+
+// markDirty marks formulas which depend on name directly or indirectly as dirty.
+func (s *Sheet) markDirty(name string) {
+	for _, dependent := range s.dependents[name] {
+		if !s.dirty[dependent] {
+			s.dirty[dependent] = true
+			s.markDirty(dependent)
+		}
+	}
+}
+
+// dependencyClosure returns name and formulas which name depends on directly or indirectly.
+func (s *Sheet) dependencyClosure(name string) map[string]bool {
+	closure := map[string]bool{name: true}
+	var visit func(name string)
+	visit = func(name string) {
+		for _, dependency := range s.dependencies[name] {
+			if !closure[dependency] {
+				closure[dependency] = true
+				visit(dependency)
+			}
+		}
+	}
+	visit(name)
+	return closure
+}
+
+// recompute evaluates dirty formulas in targets in topological order, or all dirty formulas if targets is nil,
+// and returns changes of values and errors.
+// A formula is dirty only if it depends on a changed input or a dirty formula,
+// so dependencies of formulas in targets are clean or recomputed before them.
+func (s *Sheet) recompute(targets map[string]bool) []Change {
+	var changes []Change
+	context := sheetContext{s}
+	for _, name := range s.order {
+		if !s.dirty[name] || (targets != nil && !targets[name]) {
+			continue
+		}
+
+		old, existed := s.values[name]
+		oldErr := s.errs[name]
+		value, err := s.formulas[name].Eval(context)
+		delete(s.dirty, name)
+
+		if err != nil {
+			err = errors.New(fmt.Sprintf("formula '%s': %s", name, err))
+			delete(s.values, name)
+			s.errs[name] = err
+			if oldErr == nil || oldErr.Error() != err.Error() {
+				changes = append(changes, Change{Name: name, Old: old, OldErr: oldErr, NewErr: err})
+			}
+			continue
+		}
+		delete(s.errs, name)
+		s.values[name] = value
+		if !existed || !sameValue(old, value) {
+			changes = append(changes, Change{Name: name, Old: old, New: value, OldErr: oldErr})
+		}
+	}
+	return changes
+}
+
+func (s *Sheet) notify(changes []Change) {
+	if len(changes) == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	subscribers := make([]func(Change), 0, len(s.subscribers))
+	for id := 0; id < s.nextID; id++ {
+		if f, ok := s.subscribers[id]; ok {
+			subscribers = append(subscribers, f)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, change := range changes {
+		for _, f := range subscribers {
+			f(change)
+		}
+	}
+}
+
+// sheetContext is Context used to evaluate formulas of Sheet while the lock is held.
+type sheetContext struct {
+	sheet *Sheet
+}
+
+func (c sheetContext) Value(key string) (float64, error) {
+	return c.sheet.value(key)
+}
+
+// sameValue reports whether a and b are the same value, treating NaNs as the same.
+func sameValue(a, b float64) bool {
+	return a == b || (math.IsNaN(a) && math.IsNaN(b))
+}
@@ -0,0 +1,139 @@
+package goculator
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func newTestFormulaSet() *FormulaSet {
+	set := NewFormulaSet()
+	set.Add("gross", "price * qty")
+	set.Add("net", "gross - discount")
+	set.Add("shipping", "weight * 0 + 5")
+	set.Add("total", "net + shipping")
+	return set
+}
+
+func TestSheetLazy(t *testing.T) {
+	assert := assert.New(t)
+
+	sheet, err := NewSheet(newTestFormulaSet(), map[string]float64{"price": 10, "qty": 3, "discount": 5, "weight": 2}, false)
+	assert.NoError(err)
+
+	var changes []Change
+	sheet.Subscribe(func(change Change) {
+		changes = append(changes, change)
+	})
+
+	total, err := sheet.Value("total")
+	assert.NoError(err)
+	assert.Equal(float64(30), total)
+	assert.Empty(changes)
+
+	assert.NoError(sheet.Set("discount", 7))
+	assert.Equal([]string{"net", "total"}, sheet.Dirty())
+
+	net, err := sheet.Value("net")
+	assert.NoError(err)
+	assert.Equal(float64(23), net)
+	assert.Equal([]Change{Change{Name: "net", Old: 25, New: 23}}, changes)
+	assert.Equal([]string{"total"}, sheet.Dirty())
+
+	sheet.Recompute()
+	assert.Empty(sheet.Dirty())
+	assert.Equal([]Change{Change{Name: "net", Old: 25, New: 23}, Change{Name: "total", Old: 30, New: 28}}, changes)
+
+	// shipping does not change, so only dirty formulas are recomputed and nothing is notified
+	changes = nil
+	assert.NoError(sheet.Set("weight", 4))
+	assert.Equal([]string{"shipping", "total"}, sheet.Dirty())
+	sheet.Recompute()
+	assert.Empty(changes)
+
+	assert.EqualError(sheet.Set("net", 1), "'net' is a formula, not an input")
+}
+
+func TestSheetEager(t *testing.T) {
+	assert := assert.New(t)
+
+	sheet, err := NewSheet(newTestFormulaSet(), map[string]float64{"price": 10, "qty": 3, "discount": 5}, true)
+	assert.NoError(err)
+
+	_, err = sheet.Value("shipping")
+	assert.EqualError(err, "formula 'shipping': no value for key 'weight'")
+
+	var changes []Change
+	unsubscribe := sheet.Subscribe(func(change Change) {
+		changes = append(changes, change)
+	})
+
+	assert.NoError(sheet.Set("qty", 4))
+	assert.Empty(sheet.Dirty())
+	assert.Equal([]Change{Change{Name: "gross", Old: 30, New: 40}, Change{Name: "net", Old: 25, New: 35}}, changes)
+
+	assert.NoError(sheet.Set("weight", 1))
+	total, err := sheet.Value("total")
+	assert.NoError(err)
+	assert.Equal(float64(40), total)
+
+	changes = nil
+	unsubscribe()
+	assert.NoError(sheet.Set("qty", 5))
+	assert.Empty(changes)
+
+	// Sheet is Context of other expressions.
+	result, err := goWith(New("total * 2"), sheet)
+	assert.NoError(err)
+	assert.Equal(float64(100), result)
+}
+
+func TestSheetErrorChange(t *testing.T) {
+	assert := assert.New(t)
+
+	set := NewFormulaSet(WithPolicy(PolicyErrorOnDivisionByZero))
+	set.Add("ratio", "a / b + c")
+	sheet, err := NewSheet(set, map[string]float64{"a": 6, "b": 2, "c": 0}, true)
+	assert.NoError(err)
+
+	var changes []Change
+	sheet.Subscribe(func(change Change) {
+		changes = append(changes, change)
+	})
+
+	// a change from a value to an error and back is notified, but the same error is not notified again
+	assert.NoError(sheet.Set("b", 0))
+	assert.NoError(sheet.Set("c", 1))
+	assert.NoError(sheet.Set("b", 3))
+	if assert.Len(changes, 2) {
+		assert.Equal("ratio", changes[0].Name)
+		assert.Equal(float64(3), changes[0].Old)
+		assert.NoError(changes[0].OldErr)
+		assert.EqualError(changes[0].NewErr, "formula 'ratio': division by zero: 6 / 0 at position 2")
+		assert.Equal(changes[0].NewErr, changes[1].OldErr)
+		assert.Equal(float64(3), changes[1].New)
+		assert.NoError(changes[1].NewErr)
+	}
+}
+
+func TestSheetConcurrentFormulaSet(t *testing.T) {
+	assert := assert.New(t)
+
+	set := newTestFormulaSet()
+	sheet, err := NewSheet(set, map[string]float64{"price": 10, "qty": 3, "discount": 5, "weight": 2}, true)
+	assert.NoError(err)
+
+	// Sheet does not read FormulaSet after NewSheet, so adding formulas does not race with Sheet
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			set.Add(fmt.Sprintf("f%d", i), "total + 1")
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		assert.NoError(sheet.Set("qty", float64(i)))
+		sheet.Value("total")
+	}
+	<-done
+}
@@ -0,0 +1,221 @@
+package goculator
+
+import (
+	"fmt"
+	"math"
+)
+
+// Simplify returns new Expression with constant subexpressions folded and identities applied.
+//
+// Without fastMath, only transformations which keep the result of IEEE 754 arithmetic
+// including NaN and signed zero are applied, such as x * 1 → x, x - 0 → x and x - -y → x + y.
+// Constant subexpressions are folded only if the result is finite.
+//
+// With fastMath, transformations which can change NaN, infinity or signed zero results are applied too,
+// such as x + 0 → x, x * 0 → 0, x - x → 0, x / x → 1, and constants are reassociated like 2 * (3 * x) → 6 * x.
+func (e *Expression) Simplify(fastMath bool) *Expression {
+	s := simplifier{fastMath: fastMath}
+	result := &Expression{input: e.input, options: e.options}
+	if e.root != nil {
+		result.root = s.simplify(e.root)
+	}
+	return result
+}
+
+type simplifier struct {
+	fastMath bool
+}
+
+func (s *simplifier) simplify(n node) node {
+	switch n := n.(type) {
+	case *numberNode:
+		return n
+	case *variableNode:
+		result := &variableNode{path: make([]pathElementNode, len(n.path)), s: n.s}
+		for i, element := range n.path {
+			result.path[i] = element
+			if element.index != nil {
+				result.path[i].index = s.simplify(element.index)
+			}
+		}
+		return result
+	case *unaryNode:
+		return s.simplifyUnary(n.op, s.simplify(n.operand), n.s)
+	case *binaryNode:
+		return s.simplifyBinary(n.op, s.simplify(n.left), s.simplify(n.right), n.s, n.opPos)
+	}
+	panic(fmt.Sprintf("unknown node %T", n))
+}
+
+func (s *simplifier) simplifyUnary(op TokenType, operand node, span Span) node {
+	// +x → x
+	if op == TokenTypePLUS {
+		return operand
+	}
+	// --x → x
+	if inner, ok := operand.(*unaryNode); ok && inner.op == TokenTypeMINUS {
+		return inner.operand
+	}
+	return &unaryNode{op: op, operand: operand, s: span}
+}
+
+func (s *simplifier) simplifyBinary(op TokenType, left, right node, span Span, opPos Position) node {
+	leftValue, leftConstant := constantValue(left)
+	rightValue, rightConstant := constantValue(right)
+
+	if leftConstant && rightConstant {
+		if result := binary(op, leftValue, rightValue); !math.IsNaN(result) && !math.IsInf(result, 0) {
+			return newConstantNode(result, span)
+		}
+	}
+
+	switch op {
+	case TokenTypePLUS:
+		// x + -0 → x, -0 + x → x
+		if rightConstant && isNegativeZero(rightValue) {
+			return left
+		}
+		if leftConstant && isNegativeZero(leftValue) {
+			return right
+		}
+		// x + -y → x - y
+		if negated, ok := right.(*unaryNode); ok && negated.op == TokenTypeMINUS {
+			return s.simplifyBinary(TokenTypeMINUS, left, negated.operand, span, opPos)
+		}
+		if s.fastMath {
+			if rightConstant && rightValue == 0 {
+				return left
+			}
+			if leftConstant && leftValue == 0 {
+				return right
+			}
+		}
+	case TokenTypeMINUS:
+		// x - 0 → x
+		if rightConstant && rightValue == 0 && !math.Signbit(rightValue) {
+			return left
+		}
+		// x - -y → x + y
+		if negated, ok := right.(*unaryNode); ok && negated.op == TokenTypeMINUS {
+			return s.simplifyBinary(TokenTypePLUS, left, negated.operand, span, opPos)
+		}
+		if s.fastMath {
+			if rightConstant && rightValue == 0 {
+				return left
+			}
+			if leftConstant && leftValue == 0 {
+				return s.simplifyUnary(TokenTypeMINUS, right, span)
+			}
+			if equalNodes(left, right) {
+				return newConstantNode(0, span)
+			}
+		}
+	case TokenTypeMULTI:
+		// x * 1 → x, x * -1 → -x
+		if rightConstant && math.Abs(rightValue) == 1 {
+			return s.sign(rightValue, left, span)
+		}
+		if leftConstant && math.Abs(leftValue) == 1 {
+			return s.sign(leftValue, right, span)
+		}
+		if s.fastMath {
+			if (rightConstant && rightValue == 0) || (leftConstant && leftValue == 0) {
+				return newConstantNode(0, span)
+			}
+		}
+	case TokenTypeDIV:
+		// x / 1 → x, x / -1 → -x
+		if rightConstant && math.Abs(rightValue) == 1 {
+			return s.sign(rightValue, left, span)
+		}
+		if s.fastMath {
+			if leftConstant && leftValue == 0 {
+				return newConstantNode(0, span)
+			}
+			if equalNodes(left, right) {
+				return newConstantNode(1, span)
+			}
+		}
+	}
+
+	if s.fastMath {
+		if result, ok := s.reassociate(op, left, right, span, opPos); ok {
+			return result
+		}
+	}
+	return &binaryNode{op: op, left: left, right: right, s: span, opPos: opPos}
+}
+
+// sign returns operand if sign is positive, otherwise -operand.
+func (s *simplifier) sign(sign float64, operand node, span Span) node {
+	if sign > 0 {
+		return operand
+	}
+	return s.simplifyUnary(TokenTypeMINUS, operand, span)
+}
+
+// reassociate folds constants of nested additions or multiplications,
+// e.g. 2 * (3 * x) → 6 * x and (1 + x) + 2 → x + 3.
+func (s *simplifier) reassociate(op TokenType, left, right node, span Span, opPos Position) (node, bool) {
+	if op != TokenTypePLUS && op != TokenTypeMULTI {
+		return nil, false
+	}
+
+	constant, constantOk := constantValue(left)
+	other := right
+	if !constantOk {
+		constant, constantOk = constantValue(right)
+		other = left
+	}
+	if !constantOk {
+		return nil, false
+	}
+
+	inner, ok := other.(*binaryNode)
+	if !ok || inner.op != op {
+		return nil, false
+	}
+
+	innerConstant, innerOk := constantValue(inner.left)
+	innerOther := inner.right
+	if !innerOk {
+		innerConstant, innerOk = constantValue(inner.right)
+		innerOther = inner.left
+	}
+	if !innerOk {
+		return nil, false
+	}
+
+	folded := binary(op, constant, innerConstant)
+	if math.IsNaN(folded) || math.IsInf(folded, 0) {
+		return nil, false
+	}
+	if op == TokenTypePLUS {
+		return s.simplifyBinary(op, innerOther, newConstantNode(folded, span), span, opPos), true
+	}
+	return s.simplifyBinary(op, newConstantNode(folded, span), innerOther, span, opPos), true
+}
+
+// constantValue returns the value of number or negated number.
+func constantValue(n node) (float64, bool) {
+	switch n := n.(type) {
+	case *numberNode:
+		return n.value, true
+	case *unaryNode:
+		value, ok := constantValue(n.operand)
+		return unary(n.op, value), ok
+	}
+	return 0, false
+}
+
+// newConstantNode returns numberNode of value. Negative value is returned as negated numberNode.
+func newConstantNode(value float64, span Span) node {
+	if math.Signbit(value) {
+		return &unaryNode{op: TokenTypeMINUS, operand: &numberNode{value: -value, s: span}, s: span}
+	}
+	return &numberNode{value: value, s: span}
+}
+
+func isNegativeZero(value float64) bool {
+	return value == 0 && math.Signbit(value)
+}
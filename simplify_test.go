@@ -0,0 +1,79 @@
+package goculator
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+func TestSimplify(t *testing.T) {
+	assert := assert.New(t)
+	var testdata = []struct {
+		input    string
+		fastMath bool
+		result   string
+	}{
+		{"x * 1 + 0", false, "x + 0"},
+		{"x * 1 + 0", true, "x"},
+		{"(2 * 3) * rate", false, "6 * rate"},
+		{"2 * (3 * rate)", false, "2 * (3 * rate)"},
+		{"2 * (3 * rate)", true, "6 * rate"},
+		{"(1 + x) + 2", true, "x + 3"},
+		{"1 / x / 1 - 0", false, "1 / x"},
+		{"x * -1 + y / -1", false, "-x - y"},
+		{"x - -y", false, "x + y"},
+		{"x + -0", false, "x"},
+		{"--x", false, "x"},
+		{"+x", false, "x"},
+		{"2 - 5 * x", false, "2 - 5 * x"},
+		{"2 - 5 + x", false, "-3 + x"},
+		{"1 / 0 + x", false, "1 / 0 + x"},
+		{"x * 0", false, "x * 0"},
+		{"x * 0", true, "0"},
+		{"x - x", false, "x - x"},
+		{"x - x + y / y", true, "1"},
+		{"0 - x", true, "-x"},
+		{"items[1 + 1].price * 1", false, "items[2].price"},
+		{"", false, ""},
+	}
+
+	for _, data := range testdata {
+		expr, err := Parse(data.input)
+		if !assert.NoError(err, data.input) {
+			continue
+		}
+		assert.Equal(data.result, expr.Simplify(data.fastMath).String(), data.input)
+	}
+}
+
+// TestSimplifyIEEE checks that Simplify without fast-math keeps results of special values bit for bit.
+func TestSimplifyIEEE(t *testing.T) {
+	assert := assert.New(t)
+	var inputs = []string{
+		"x * 1 + 0",
+		"1 * x / 1 - 0",
+		"x * -1 + y / -1",
+		"x - -y + -0",
+		"-0 + x + -y",
+		"--x - +y",
+		"(2 * 3) * x - (1 - 4)",
+	}
+	values := []float64{0, math.Copysign(0, -1), 1.5, -2, math.Inf(1), math.Inf(-1), math.NaN()}
+
+	for _, input := range inputs {
+		expr, _ := Parse(input)
+		simplified := expr.Simplify(false)
+		for _, x := range values {
+			for _, y := range values {
+				context := NewDefaultContext(map[string]float64{"x": x, "y": y})
+				expected, _ := expr.Eval(context)
+				result, _ := simplified.Eval(context)
+				if math.IsNaN(expected) {
+					assert.True(math.IsNaN(result), input)
+				} else {
+					assert.Equal(math.Float64bits(expected), math.Float64bits(result), "%s with x=%v y=%v", input, x, y)
+				}
+			}
+		}
+	}
+}
@@ -1,5 +1,10 @@
 package goculator
 
+import (
+	"fmt"
+	"strconv"
+)
+
 // TokenType is specific types of token.
 type TokenType string
 
@@ -22,6 +27,17 @@ const (
 	TokenTypeLPARAN TokenType = "LPARAN"
 	// TokenTypeLPARAN represents token with ")"
 	TokenTypeRPARAN TokenType = "RPARAN"
+	// TokenTypeDOT represents token with "." for member access
+	TokenTypeDOT TokenType = "DOT"
+	// TokenTypeLBRACKET represents token with "["
+	TokenTypeLBRACKET TokenType = "LBRACKET"
+	// TokenTypeRBRACKET represents token with "]"
+	TokenTypeRBRACKET TokenType = "RBRACKET"
+	// TokenTypeSEP represents token with the argument separator of Locale
+	TokenTypeSEP TokenType = "SEP"
+	// TokenTypeCOMMENT represents token with line comment starting with "#" or "//", or block comment "/* */".
+	// Its value is the whole comment including the comment markers.
+	TokenTypeCOMMENT TokenType = "COMMENT"
 	// TokenTypeNone represents token with value which cannot be tokenized.
 	TokenTypeNONE TokenType = "NONE"
 )
@@ -31,3 +47,25 @@ type Token struct {
 	Type  TokenType
 	Value string
 }
+
+// Position is the location of a Token in the input text.
+type Position struct {
+	// Offset is the byte offset, starting at 0.
+	Offset int
+	// Rune is the rune (character) offset, starting at 0.
+	Rune int
+}
+
+// String returns the rune offset, followed by the byte offset if they differ.
+func (p Position) String() string {
+	if p.Rune == p.Offset {
+		return strconv.Itoa(p.Rune)
+	}
+	return fmt.Sprintf("%d (byte %d)", p.Rune, p.Offset)
+}
+
+// Span is the range of the input text from Start to End. End is the position right after the last character.
+type Span struct {
+	Start Position
+	End   Position
+}
@@ -0,0 +1,224 @@
+package goculator
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// TraceKind is the kind of TraceStep.
+type TraceKind string
+
+const (
+	// TraceNumber is a number literal.
+	TraceNumber TraceKind = "number"
+	// TraceVariable is a variable lookup from Context.
+	TraceVariable TraceKind = "variable"
+	// TraceOperator is an application of a unary or binary operator.
+	TraceOperator TraceKind = "operator"
+)
+
+// TraceStep is a step of evaluation recorded by Trace. Steps of operands and index expressions
+// are in Steps in the order of evaluation.
+type TraceStep struct {
+	Kind TraceKind
+	// Text is the sub-expression of the step as printed by Expression.String.
+	Text string
+	// Op is the operator of TraceOperator, e.g. "*" or "-" for negation.
+	Op string
+	// Name is the key of TraceVariable looked up from Context.
+	Name string
+	// Operands are the operands of TraceOperator.
+	Operands []float64
+	Result   float64
+	Span     Span
+	// Err is the error if evaluation failed at this step or in Steps.
+	Err   error
+	Steps []*TraceStep
+	// decimal is the decimal mark of values in Tree.
+	decimal rune
+}
+
+// Trace evaluates Expression like Eval and records every variable lookup and operator application.
+// The returned TraceStep is the root of evaluation, which is recorded up to the failed step if evaluation fails.
+// It is nil if Expression is empty.
+func (e *Expression) Trace(context Context) (*TraceStep, error) {
+	if e.root == nil {
+		return nil, nil
+	}
+	t := &tracer{decimal: e.options.locale.Decimal}
+	ev := evaluator{context: context, maxSteps: e.options.limits.MaxSteps, policy: e.options.policy, tracer: t}
+	_, err := ev.eval(e.root)
+	return t.root, err
+}
+
+// Trace calculates like Go and records evaluation steps. See Expression.Trace.
+func (c *Calculator) Trace() (*TraceStep, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return c.expr.Trace(c.bound())
+}
+
+// tracer builds TraceSteps while evaluator walks the tree.
+type tracer struct {
+	// decimal is the decimal mark of Text.
+	decimal rune
+	root    *TraceStep
+	// stack is the steps being evaluated, from the root to the current step.
+	stack []*TraceStep
+}
+
+func (t *tracer) begin(n node) *TraceStep {
+	p := printer{decimal: t.decimal}
+	p.print(n, false)
+	step := &TraceStep{Text: p.buf.String(), Span: n.span(), decimal: t.decimal}
+	switch n := n.(type) {
+	case *numberNode:
+		step.Kind = TraceNumber
+	case *variableNode:
+		step.Kind = TraceVariable
+		step.Name = variableKey(n)
+	case *binaryNode:
+		step.Kind = TraceOperator
+		step.Op = operatorStrings[n.op]
+	case *unaryNode:
+		step.Kind = TraceOperator
+		step.Op = operatorStrings[n.op]
+	}
+
+	if len(t.stack) == 0 {
+		t.root = step
+	} else {
+		parent := t.stack[len(t.stack)-1]
+		parent.Steps = append(parent.Steps, step)
+	}
+	t.stack = append(t.stack, step)
+	return step
+}
+
+func (t *tracer) end(step *TraceStep, result float64, err error) {
+	t.stack = t.stack[:len(t.stack)-1]
+	if step.Kind == TraceOperator {
+		for _, operand := range step.Steps {
+			if operand.Err != nil {
+				break
+			}
+			step.Operands = append(step.Operands, operand.Result)
+		}
+	}
+	if err != nil {
+		step.Err = err
+		return
+	}
+	step.Result = result
+}
+
+// failedInside reports whether evaluation failed in Steps rather than at step itself.
+func (s *TraceStep) failedInside() bool {
+	return len(s.Steps) > 0 && s.Steps[len(s.Steps)-1].Err != nil
+}
+
+// Tree returns the trace as an indented tree, one step per line, with values printed with the decimal mark
+// of Locale of Expression, e.g.
+//
+//	(price - cost) * qty = 6 * 5 = 30
+//	  price - cost = 10 - 4 = 6
+//	    price = 10
+//	    cost = 4
+//	  qty = 5
+func (s *TraceStep) Tree() string {
+	var b strings.Builder
+	s.writeTree(&b, 0)
+	return b.String()
+}
+
+func (s *TraceStep) writeTree(b *strings.Builder, depth int) {
+	b.WriteString(strings.Repeat("  ", depth))
+	b.WriteString(s.Text)
+	switch {
+	case s.Err != nil && s.failedInside():
+		b.WriteString(" = failed")
+	case s.Kind == TraceOperator && len(s.Operands) == 1:
+		fmt.Fprintf(b, " = %s(%s)", s.Op, s.format(s.Operands[0]))
+	case s.Kind == TraceOperator && len(s.Operands) == 2:
+		fmt.Fprintf(b, " = %s %s %s", s.format(s.Operands[0]), s.Op, s.format(s.Operands[1]))
+	}
+	if s.Err != nil {
+		if !s.failedInside() {
+			fmt.Fprintf(b, " = error: %s", s.Err)
+		}
+	} else if s.Kind != TraceNumber {
+		fmt.Fprintf(b, " = %s", s.format(s.Result))
+	}
+	b.WriteString("\n")
+
+	for _, step := range s.Steps {
+		step.writeTree(b, depth+1)
+	}
+}
+
+// format returns value with the decimal mark of the step.
+func (s *TraceStep) format(value float64) string {
+	return replaceDecimal(formatFloat(value), s.decimal)
+}
+
+// MarshalJSON returns the trace as JSON. NaN and ±Inf are strings "NaN", "+Inf" and "-Inf",
+// and result is omitted if the step failed.
+func (s *TraceStep) MarshalJSON() ([]byte, error) {
+	step := traceStepJSON{
+		Kind: s.Kind,
+		Text: s.Text,
+		Op:   s.Op,
+		Name: s.Name,
+		Span: spanJSON{
+			Start: positionJSON{Offset: s.Span.Start.Offset, Rune: s.Span.Start.Rune},
+			End:   positionJSON{Offset: s.Span.End.Offset, Rune: s.Span.End.Rune},
+		},
+		Steps: s.Steps,
+	}
+	for _, operand := range s.Operands {
+		step.Operands = append(step.Operands, jsonFloat(operand))
+	}
+	if s.Err != nil {
+		step.Error = s.Err.Error()
+	} else {
+		result := jsonFloat(s.Result)
+		step.Result = &result
+	}
+	return json.Marshal(step)
+}
+
+type traceStepJSON struct {
+	Kind     TraceKind    `json:"kind"`
+	Text     string       `json:"text"`
+	Op       string       `json:"op,omitempty"`
+	Name     string       `json:"name,omitempty"`
+	Operands []jsonFloat  `json:"operands,omitempty"`
+	Result   *jsonFloat   `json:"result,omitempty"`
+	Error    string       `json:"error,omitempty"`
+	Span     spanJSON     `json:"span"`
+	Steps    []*TraceStep `json:"steps,omitempty"`
+}
+
+type spanJSON struct {
+	Start positionJSON `json:"start"`
+	End   positionJSON `json:"end"`
+}
+
+type positionJSON struct {
+	Offset int `json:"offset"`
+	Rune   int `json:"rune"`
+}
+
+// jsonFloat is float64 which is encoded as a string if it is not finite.
+type jsonFloat float64
+
+func (f jsonFloat) MarshalJSON() ([]byte, error) {
+	value := float64(f)
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return json.Marshal(formatFloat(value))
+	}
+	return []byte(formatFloat(value)), nil
+}
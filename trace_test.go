@@ -0,0 +1,93 @@
+package goculator
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestTrace(t *testing.T) {
+	assert := assert.New(t)
+
+	context := NewNestedContext(map[string]interface{}{
+		"price": 10, "cost": 4, "qty": 5,
+		"items": []map[string]float64{{"price": 1}, {"price": 2}},
+	})
+	tests := []struct {
+		input string
+		tree  string
+		err   string
+	}{
+		{"(price - cost) * qty", "(price - cost) * qty = 6 * 5 = 30\n" +
+			"  price - cost = 10 - 4 = 6\n" +
+			"    price = 10\n" +
+			"    cost = 4\n" +
+			"  qty = 5\n", ""},
+		{"-qty + 2", "-qty + 2 = -5 + 2 = -3\n" +
+			"  -qty = -(5) = -5\n" +
+			"    qty = 5\n" +
+			"  2\n", ""},
+		{"items[qty - 4].price", "items[qty - 4].price = 2\n" +
+			"  qty - 4 = 5 - 4 = 1\n" +
+			"    qty = 5\n" +
+			"    4\n", ""},
+		{"price / (tax + 1)", "price / (tax + 1) = failed\n" +
+			"  price = 10\n" +
+			"  tax + 1 = failed\n" +
+			"    tax = error: no value for key 'tax'\n", "no value for key 'tax'"},
+	}
+
+	for _, test := range tests {
+		expr, err := Parse(test.input)
+		if !assert.NoError(err, test.input) {
+			continue
+		}
+		step, err := expr.Trace(context)
+		if test.err != "" {
+			assert.EqualError(err, test.err, test.input)
+		} else {
+			assert.NoError(err, test.input)
+		}
+		if assert.NotNil(step, test.input) {
+			assert.Equal(test.tree, step.Tree(), test.input)
+		}
+	}
+
+	calc := New("1 / x", WithPolicy(PolicyErrorOnDivisionByZero))
+	calc.Bind(NewDefaultContext(map[string]float64{"x": 0}))
+	step, err := calc.Trace()
+	assert.IsType(&ArithmeticError{}, err)
+	assert.Equal("1 / x = 1 / 0 = error: division by zero: 1 / 0 at position 2\n  1\n  x = 0\n", step.Tree())
+
+	// values are printed with the decimal mark of the locale like the expression
+	calc = New("1,5 * x", WithLocale(DecimalCommaLocale))
+	calc.Bind(NewDefaultContext(map[string]float64{"x": 2.5}))
+	step, err = calc.Trace()
+	assert.NoError(err)
+	assert.Equal("1,5 * x = 1,5 * 2,5 = 3,75\n  1,5\n  x = 2,5\n", step.Tree())
+
+	step, err = New("").Trace()
+	assert.NoError(err)
+	assert.Nil(step)
+}
+
+func TestTraceJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	expr, _ := Parse("x * 2")
+	step, err := expr.Trace(NewDefaultContext(map[string]float64{"x": 1e308}))
+	assert.NoError(err)
+
+	data, err := json.Marshal(step)
+	assert.NoError(err)
+	assert.JSONEq(`{
+		"kind": "operator", "text": "x * 2", "op": "*", "operands": [1e308, 2], "result": "+Inf",
+		"span": {"start": {"offset": 0, "rune": 0}, "end": {"offset": 5, "rune": 5}},
+		"steps": [
+			{"kind": "variable", "text": "x", "name": "x", "result": 1e308,
+				"span": {"start": {"offset": 0, "rune": 0}, "end": {"offset": 1, "rune": 1}}},
+			{"kind": "number", "text": "2", "result": 2,
+				"span": {"start": {"offset": 4, "rune": 4}, "end": {"offset": 5, "rune": 5}}}
+		]
+	}`, string(data))
+}